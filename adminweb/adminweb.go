@@ -21,21 +21,26 @@
 package adminweb
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/daaku/go.httpdev"
 	"github.com/daaku/go.trustforward"
 	"github.com/daaku/go.viewvar"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
 )
 
 type Handler struct {
-	Forwarded *trustforward.Forwarded
-	SkipHTTPS bool
-	Path      string
+	Forwarded  *trustforward.Forwarded
+	SkipHTTPS  bool
+	Path       string
+	EmpChecker *empcheck.Checker
 
 	mux http.Handler
 }
@@ -53,6 +58,11 @@ func (h *Handler) Init() {
 	mux.HandleFunc(root+"vars/", viewvar.Json)
 	mux.HandleFunc(root+"env/", h.envHandler)
 	mux.HandleFunc(root+"sleep/", httpdev.Sleep)
+	if h.EmpChecker != nil {
+		mux.HandleFunc(root+"empcheck/", h.empCheckStatsHandler)
+		mux.HandleFunc(root+"empcheck/check", h.empCheckHandler)
+		mux.HandleFunc(root+"empcheck/invalidate", h.empCheckInvalidateHandler)
+	}
 	h.mux = mux
 }
 
@@ -71,3 +81,68 @@ func (h *Handler) envHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, s)
 	}
 }
+
+// Shows the empcheck cache counters.
+func (h *Handler) empCheckStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.EmpChecker.Stats())
+}
+
+// Checks the uid bypassing the cache, and caches the fresh result.
+func (h *Handler) empCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.checkPost(w, r) {
+		return
+	}
+	id, ok := uidParam(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"uid":        id,
+		"isEmployee": h.EmpChecker.Refresh(r.Context(), id),
+	})
+}
+
+// Drops the cached result for the uid.
+func (h *Handler) empCheckInvalidateHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.checkPost(w, r) {
+		return
+	}
+	id, ok := uidParam(w, r)
+	if !ok {
+		return
+	}
+	h.EmpChecker.Invalidate(id)
+	writeJSON(w, map[string]interface{}{"uid": id, "invalidated": true})
+}
+
+// Endpoints which change state only accept POSTs, and refuse those sent by
+// other origins, so links, prefetching and other sites cannot trigger them.
+func (h *Handler) checkPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != h.Forwarded.Host(r) {
+			http.Error(w, "cross origin request refused", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+func uidParam(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.FormValue("uid"), 10, 64)
+	if err != nil || id == 0 {
+		http.Error(w, "a numeric uid parameter is required", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,62 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package adminweb_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.trustforward"
+	"github.com/facebookgo/ensure"
+	"github.com/fbsamples/fbrell/adminweb"
+	"github.com/fbsamples/fbrell/cache"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
+)
+
+func TestEmpCheckRequiresPost(t *testing.T) {
+	t.Parallel()
+	h := &adminweb.Handler{
+		Forwarded:  &trustforward.Forwarded{},
+		SkipHTTPS:  true,
+		Path:       "admin",
+		EmpChecker: &empcheck.Checker{Cache: &cache.Cache[uint64, bool]{}},
+	}
+	h.Init()
+	for _, endpoint := range []string{"check", "invalidate"} {
+		target := "http://rell.test/admin/empcheck/" + endpoint + "?uid=4"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		ensure.DeepEqual(t, w.Code, http.StatusMethodNotAllowed, endpoint)
+
+		r := httptest.NewRequest("POST", target, nil)
+		r.Header.Set("Origin", "https://evil.test")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		ensure.DeepEqual(t, w.Code, http.StatusForbidden, endpoint)
+	}
+
+	r := httptest.NewRequest("POST", "http://rell.test/admin/empcheck/invalidate?uid=4", nil)
+	r.Header.Set("Origin", "http://rell.test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+}
@@ -0,0 +1,192 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package cache provides typed LRU caches with TTLs and stats, optionally
+// backed by a Store shared between caches and restarts.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/groupcache/lru"
+)
+
+// Store is a shared backing for caches. Keys are prefixed with the cache
+// name, and values are JSON encoded.
+type Store interface {
+	Get(key string) (value []byte, expires time.Time, ok bool)
+	Set(key string, value []byte, expires time.Time)
+	Delete(key string)
+}
+
+// Stats are the counters for a Cache.
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Len    int   `json:"len"`
+}
+
+type entry[V any] struct {
+	Value   V
+	Expires time.Time // zero if it never expires
+}
+
+func (e entry[V]) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// Cache is a LRU cache which is safe for concurrent use. The zero value is
+// an unbounded cache whose entries never expire. Entries evicted from memory
+// are also deleted from the Store, so it is bounded by the same Size.
+type Cache[K comparable, V any] struct {
+	Name  string        // prefixes the keys in the Store
+	Size  int           // maximum number of entries in memory, 0 is unbounded
+	TTL   time.Duration // used by Add, 0 never expires
+	Store Store         // optional shared backing
+	Clock clock.Clock   // defaults to the real clock
+
+	mu     sync.Mutex
+	lru    *lru.Cache
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Get the unexpired value for the key, from memory or the Store.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.getLocked(key, now)
+	c.mu.Unlock()
+	if !ok && c.Store != nil {
+		e, ok = c.load(key, now)
+	}
+	if !ok {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	c.hits.Add(1)
+	return e.Value, true
+}
+
+// Add the value with the default TTL.
+func (c *Cache[K, V]) Add(key K, value V) {
+	c.AddTTL(key, value, c.TTL)
+}
+
+// AddTTL adds the value expiring after the given TTL, or never if it is 0.
+// Values which cannot be JSON encoded are only cached in memory.
+func (c *Cache[K, V]) AddTTL(key K, value V, ttl time.Duration) {
+	e := entry[V]{Value: value}
+	if ttl != 0 {
+		e.Expires = c.now().Add(ttl)
+	}
+	c.mu.Lock()
+	c.addLocked(key, e)
+	c.mu.Unlock()
+	if c.Store != nil {
+		if raw, err := json.Marshal(value); err == nil {
+			c.Store.Set(c.storeKey(key), raw, e.Expires)
+		}
+	}
+}
+
+// Remove the key from memory and the Store.
+func (c *Cache[K, V]) Remove(key K) {
+	c.mu.Lock()
+	if c.lru != nil {
+		c.lru.Remove(key)
+	}
+	c.mu.Unlock()
+	if c.Store != nil {
+		c.Store.Delete(c.storeKey(key))
+	}
+}
+
+// Stats returns the current counters.
+func (c *Cache[K, V]) Stats() Stats {
+	s := Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	c.mu.Lock()
+	if c.lru != nil {
+		s.Len = c.lru.Len()
+	}
+	c.mu.Unlock()
+	return s
+}
+
+func (c *Cache[K, V]) getLocked(key K, now time.Time) (entry[V], bool) {
+	if c.lru == nil {
+		return entry[V]{}, false
+	}
+	v, ok := c.lru.Get(key)
+	if !ok {
+		return entry[V]{}, false
+	}
+	e := v.(entry[V])
+	if e.expired(now) {
+		c.lru.Remove(key)
+		return entry[V]{}, false
+	}
+	return e, true
+}
+
+func (c *Cache[K, V]) addLocked(key K, e entry[V]) {
+	if c.lru == nil {
+		c.lru = lru.New(c.Size)
+		if c.Store != nil {
+			c.lru.OnEvicted = func(key lru.Key, _ interface{}) {
+				c.Store.Delete(c.storeKey(key.(K)))
+			}
+		}
+	}
+	c.lru.Add(key, e)
+}
+
+// Loads the key from the Store into memory.
+func (c *Cache[K, V]) load(key K, now time.Time) (entry[V], bool) {
+	raw, expires, ok := c.Store.Get(c.storeKey(key))
+	if !ok {
+		return entry[V]{}, false
+	}
+	e := entry[V]{Expires: expires}
+	if e.expired(now) || json.Unmarshal(raw, &e.Value) != nil {
+		return entry[V]{}, false
+	}
+	c.mu.Lock()
+	c.addLocked(key, e)
+	c.mu.Unlock()
+	return e, true
+}
+
+func (c *Cache[K, V]) storeKey(key K) string {
+	return fmt.Sprintf("%s:%v", c.Name, key)
+}
+
+func (c *Cache[K, V]) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+	return c.Clock.Now()
+}
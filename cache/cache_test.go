@@ -0,0 +1,132 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package cache_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/facebookgo/ensure"
+	"github.com/fbsamples/fbrell/cache"
+)
+
+func TestCacheTTL(t *testing.T) {
+	t.Parallel()
+	c := clock.NewMock()
+	ca := &cache.Cache[uint64, string]{TTL: time.Hour, Clock: c}
+	ca.Add(1, "one")
+	ca.AddTTL(2, "two", time.Minute)
+	ca.AddTTL(3, "three", 0)
+
+	c.Add(time.Minute)
+	v, ok := ca.Get(1)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, v, "one")
+	_, ok = ca.Get(2)
+	ensure.False(t, ok)
+
+	c.Add(365 * 24 * time.Hour)
+	_, ok = ca.Get(1)
+	ensure.False(t, ok)
+	_, ok = ca.Get(3)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, ca.Stats(), cache.Stats{Hits: 2, Misses: 2, Len: 1})
+}
+
+func TestCacheSizeAndRemove(t *testing.T) {
+	t.Parallel()
+	ca := &cache.Cache[string, int]{Size: 2}
+	ca.Add("a", 1)
+	ca.Add("b", 2)
+	ca.Add("c", 3)
+	_, ok := ca.Get("a")
+	ensure.False(t, ok)
+	ca.Remove("b")
+	_, ok = ca.Get("b")
+	ensure.False(t, ok)
+	v, ok := ca.Get("c")
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, v, 3)
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	t.Parallel()
+	ca := &cache.Cache[int, int]{Size: 10}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ca.Add(j, i)
+				ca.Get(j)
+			}
+		}()
+	}
+	wg.Wait()
+	ensure.DeepEqual(t, ca.Stats().Len, 10)
+}
+
+func TestFileStoreRestart(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.json")
+	store := &cache.FileStore{Path: path}
+	ensure.Nil(t, store.Load())
+	emp := &cache.Cache[uint64, bool]{Name: "emp", Store: store}
+	ns := &cache.Cache[uint64, string]{Name: "ns", Store: store}
+	emp.Add(1, true)
+	emp.AddTTL(2, true, -time.Second)
+	ns.Add(1, "rell")
+	ensure.Nil(t, store.Save())
+
+	// a new process starts with empty caches and loads the file
+	store = &cache.FileStore{Path: path}
+	ensure.Nil(t, store.Load())
+	emp = &cache.Cache[uint64, bool]{Name: "emp", Store: store}
+	ns = &cache.Cache[uint64, string]{Name: "ns", Store: store}
+	is, ok := emp.Get(1)
+	ensure.True(t, ok)
+	ensure.True(t, is)
+	_, ok = emp.Get(2)
+	ensure.False(t, ok)
+	v, ok := ns.Get(1)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, v, "rell")
+
+	emp.Remove(1)
+	_, _, ok = store.Get("emp:1")
+	ensure.False(t, ok)
+}
+
+func TestFileStoreEviction(t *testing.T) {
+	t.Parallel()
+	store := &cache.FileStore{Path: filepath.Join(t.TempDir(), "cache.json")}
+	ca := &cache.Cache[uint64, bool]{Name: "emp", Size: 1, Store: store}
+	ca.Add(1, true)
+	ca.Add(2, true)
+	_, _, ok := store.Get("emp:1")
+	ensure.False(t, ok)
+	_, _, ok = store.Get("emp:2")
+	ensure.True(t, ok)
+}
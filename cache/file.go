@@ -0,0 +1,120 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileEntry struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileStore is a Store held in memory and saved to a JSON file, so caches
+// using it are warm after a restart.
+type FileStore struct {
+	Path string
+
+	mu      sync.Mutex
+	entries map[string]fileEntry
+}
+
+func (s *FileStore) Get(key string) ([]byte, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	return e.Value, e.Expires, ok
+}
+
+func (s *FileStore) Set(key string, value []byte, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]fileEntry{}
+	}
+	s.entries[key] = fileEntry{Value: value, Expires: expires}
+}
+
+func (s *FileStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// Load replaces the entries with those saved in the file. A missing file is
+// not an error.
+func (s *FileStore) Load() error {
+	raw, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries := map[string]fileEntry{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.entries = entries
+	s.mu.Unlock()
+	return nil
+}
+
+// Save writes the unexpired entries to the file, dropping the expired ones.
+func (s *FileStore) Save() error {
+	now := time.Now()
+	s.mu.Lock()
+	if s.entries == nil {
+		s.entries = map[string]fileEntry{}
+	}
+	for key, e := range s.entries {
+		if !e.Expires.IsZero() && !now.Before(e.Expires) {
+			delete(s.entries, key)
+		}
+	}
+	raw, err := json.Marshal(s.entries)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// write and rename so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
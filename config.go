@@ -0,0 +1,226 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fbsamples/fbrell/rellenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the schema of the -config file, which may be YAML, JSON or TOML
+// based on the extension. The keys match the flag names, except apps and
+// presets which are given inline instead of as separate files:
+//
+//	dev: false
+//	addr: ":43600"
+//	admin-path: secret-admin
+//	fb-app-id: 342526215814610
+//	fb-app-secret: ...
+//	fb-app-ns: fbrell
+//	empcheck-app-id: 0
+//	empcheck-app-secret: ...
+//	empcheck: uids
+//	empcheck-uids: [4, 1234]
+//	empcheck-domains: [example.com]
+//	empcheck-header: X-Forwarded-Email
+//	empcheck-trust-proxy: false
+//	public-dir: ./public
+//	examples-dir: [./examples/db, ./more-examples]
+//	watch-examples: false
+//	saved-dir: ./saved
+//	saved-max-bytes: 104857600
+//	fake-graph: ./graph-fixture.json
+//	cache-file: ./cache.json
+//	apps:
+//	  - {id: 123, secret: ..., namespace: myapp, label: My App}
+//	presets:
+//	  fedcm-beta: {server: beta, fedcm: "true"}
+//
+// Settings in the file are overridden by RELL_ environment variables, which
+// are overridden by flags.
+type Config struct {
+	Dev                *bool               `json:"dev,omitempty" yaml:"dev,omitempty" toml:"dev,omitempty"`
+	Addr               *string             `json:"addr,omitempty" yaml:"addr,omitempty" toml:"addr,omitempty"`
+	AdminPath          *string             `json:"admin-path,omitempty" yaml:"admin-path,omitempty" toml:"admin-path,omitempty"`
+	FbAppID            *uint64             `json:"fb-app-id,omitempty" yaml:"fb-app-id,omitempty" toml:"fb-app-id,omitempty"`
+	FbAppSecret        *string             `json:"fb-app-secret,omitempty" yaml:"fb-app-secret,omitempty" toml:"fb-app-secret,omitempty"`
+	FbAppNS            *string             `json:"fb-app-ns,omitempty" yaml:"fb-app-ns,omitempty" toml:"fb-app-ns,omitempty"`
+	EmpCheckAppID      *uint64             `json:"empcheck-app-id,omitempty" yaml:"empcheck-app-id,omitempty" toml:"empcheck-app-id,omitempty"`
+	EmpCheckAppSecret  *string             `json:"empcheck-app-secret,omitempty" yaml:"empcheck-app-secret,omitempty" toml:"empcheck-app-secret,omitempty"`
+	EmpCheck           *string             `json:"empcheck,omitempty" yaml:"empcheck,omitempty" toml:"empcheck,omitempty"`
+	EmpCheckUIDs       []uint64            `json:"empcheck-uids,omitempty" yaml:"empcheck-uids,omitempty" toml:"empcheck-uids,omitempty"`
+	EmpCheckDomains    []string            `json:"empcheck-domains,omitempty" yaml:"empcheck-domains,omitempty" toml:"empcheck-domains,omitempty"`
+	EmpCheckHeader     *string             `json:"empcheck-header,omitempty" yaml:"empcheck-header,omitempty" toml:"empcheck-header,omitempty"`
+	EmpCheckTrustProxy *bool               `json:"empcheck-trust-proxy,omitempty" yaml:"empcheck-trust-proxy,omitempty" toml:"empcheck-trust-proxy,omitempty"`
+	PublicDir          *string             `json:"public-dir,omitempty" yaml:"public-dir,omitempty" toml:"public-dir,omitempty"`
+	ExamplesDir        []string            `json:"examples-dir,omitempty" yaml:"examples-dir,omitempty" toml:"examples-dir,omitempty"`
+	WatchExamples      *bool               `json:"watch-examples,omitempty" yaml:"watch-examples,omitempty" toml:"watch-examples,omitempty"`
+	SavedDir           *string             `json:"saved-dir,omitempty" yaml:"saved-dir,omitempty" toml:"saved-dir,omitempty"`
+	SavedMaxBytes      *uint64             `json:"saved-max-bytes,omitempty" yaml:"saved-max-bytes,omitempty" toml:"saved-max-bytes,omitempty"`
+	FakeGraph          *string             `json:"fake-graph,omitempty" yaml:"fake-graph,omitempty" toml:"fake-graph,omitempty"`
+	CacheFile          *string             `json:"cache-file,omitempty" yaml:"cache-file,omitempty" toml:"cache-file,omitempty"`
+	Apps               []rellenv.AppConfig `json:"apps,omitempty" yaml:"apps,omitempty" toml:"apps,omitempty"`
+	Presets            rellenv.Presets     `json:"presets,omitempty" yaml:"presets,omitempty" toml:"presets,omitempty"`
+}
+
+const redacted = "REDACTED"
+
+// LoadConfig reads the config file, rejecting unknown keys.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config %s: %s", path, err)
+	}
+	var config Config
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		dec.KnownFields(true)
+		err = dec.Decode(&config)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&config)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(content), &config)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown key %s", md.Undecoded()[0])
+		}
+	default:
+		return nil, fmt.Errorf(
+			"Unsupported config %s: expected a .yaml, .json or .toml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	if err := config.Presets.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	if _, err := rellenv.NewApps(config.Apps); err != nil {
+		return nil, fmt.Errorf("Invalid config %s: %s", path, err)
+	}
+	return &config, nil
+}
+
+// Returns the flag values for the settings in the config.
+func (c *Config) flagValues() map[string]string {
+	values := map[string]string{}
+	setString := func(name string, v *string) {
+		if v != nil {
+			values[name] = *v
+		}
+	}
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			values[name] = strconv.FormatBool(*v)
+		}
+	}
+	setUint := func(name string, v *uint64) {
+		if v != nil {
+			values[name] = strconv.FormatUint(*v, 10)
+		}
+	}
+	setBool("dev", c.Dev)
+	setString("addr", c.Addr)
+	setString("admin-path", c.AdminPath)
+	setUint("fb-app-id", c.FbAppID)
+	setString("fb-app-secret", c.FbAppSecret)
+	setString("fb-app-ns", c.FbAppNS)
+	setUint("empcheck-app-id", c.EmpCheckAppID)
+	setString("empcheck-app-secret", c.EmpCheckAppSecret)
+	setString("empcheck", c.EmpCheck)
+	if c.EmpCheckUIDs != nil {
+		var uids []string
+		for _, uid := range c.EmpCheckUIDs {
+			uids = append(uids, strconv.FormatUint(uid, 10))
+		}
+		values["empcheck-uids"] = strings.Join(uids, ",")
+	}
+	if c.EmpCheckDomains != nil {
+		values["empcheck-domains"] = strings.Join(c.EmpCheckDomains, ",")
+	}
+	setString("empcheck-header", c.EmpCheckHeader)
+	setBool("empcheck-trust-proxy", c.EmpCheckTrustProxy)
+	setString("public-dir", c.PublicDir)
+	if c.ExamplesDir != nil {
+		values["examples-dir"] = strings.Join(c.ExamplesDir, string(filepath.ListSeparator))
+	}
+	setBool("watch-examples", c.WatchExamples)
+	setString("saved-dir", c.SavedDir)
+	setUint("saved-max-bytes", c.SavedMaxBytes)
+	setString("fake-graph", c.FakeGraph)
+	setString("cache-file", c.CacheFile)
+	return values
+}
+
+// Apply sets the flags which were not given on the command line from the
+// config. Environment variables should be applied afterwards to override
+// the config.
+func (c *Config) Apply(set *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	set.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, value := range c.flagValues() {
+		if explicit[name] {
+			continue
+		}
+		if err := set.Lookup(name).Value.Set(value); err != nil {
+			return fmt.Errorf("Invalid config %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with the secrets, including the admin
+// path, replaced.
+func (c *Config) Redacted() *Config {
+	r := *c
+	redact := func(v *string) *string {
+		if v == nil || *v == "" {
+			return v
+		}
+		s := redacted
+		return &s
+	}
+	r.AdminPath = redact(c.AdminPath)
+	r.FbAppSecret = redact(c.FbAppSecret)
+	r.EmpCheckAppSecret = redact(c.EmpCheckAppSecret)
+	r.Apps = make([]rellenv.AppConfig, len(c.Apps))
+	for i, app := range c.Apps {
+		if app.Secret != "" {
+			app.Secret = redacted
+		}
+		r.Apps[i] = app
+	}
+	return &r
+}
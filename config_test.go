@@ -0,0 +1,185 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/flagenv"
+	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	ensure.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"rell.yaml": "addr: ':1'\nexamples-dir: [a, b]\napps:\n  - {id: 43, label: Other}\n" +
+			"presets:\n  beta: {server: beta}\n",
+		"rell.json": `{"addr": ":1", "examples-dir": ["a", "b"], "apps": [{"id": 43, "label": "Other"}],` +
+			`"presets": {"beta": {"server": "beta"}}}`,
+		"rell.toml": "addr = ':1'\nexamples-dir = ['a', 'b']\n[[apps]]\nid = 43\nlabel = 'Other'\n" +
+			"[presets.beta]\nserver = 'beta'\n",
+	}
+	for name, content := range cases {
+		config, err := LoadConfig(writeConfig(t, name, content))
+		ensure.Nil(t, err, name)
+		ensure.DeepEqual(t, *config.Addr, ":1", name)
+		ensure.DeepEqual(t, config.ExamplesDir, []string{"a", "b"}, name)
+		ensure.DeepEqual(t, config.Apps, []rellenv.AppConfig{{ID: 43, Label: "Other"}}, name)
+		ensure.DeepEqual(t, config.Presets, rellenv.Presets{"beta": {"server": "beta"}}, name)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string][2]string{
+		"unknown field":     {"rell.json", `{"port": 1}`},
+		"not found":         {"rell.yaml", "port: 1\n"},
+		"unknown key port":  {"rell.toml", "port = 1\n"},
+		"unknown parameter": {"rell.yaml", "presets:\n  a: {nope: x}\n"},
+		"duplicate app":     {"rell.yaml", "apps: [{id: 1}, {id: 1}]\n"},
+		"Unsupported":       {"rell.ini", ""},
+	}
+	for msg, c := range cases {
+		_, err := LoadConfig(writeConfig(t, c[0], c[1]))
+		ensure.Err(t, err, regexp.MustCompile(msg))
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	set := flag.NewFlagSet("rell", flag.ContinueOnError)
+	addr := set.String("addr", ":43600", "")
+	adminPath := set.String("admin-path", "", "")
+	savedDir := set.String("saved-dir", "./saved", "")
+	ensure.Nil(t, set.Parse([]string{"-addr", ":3"}))
+
+	config, err := LoadConfig(writeConfig(t, "rell.yaml",
+		"addr: ':1'\nadmin-path: file\nsaved-dir: file\n"))
+	ensure.Nil(t, err)
+	ensure.Nil(t, config.Apply(set))
+	t.Setenv("RELL_ADMIN_PATH", "env")
+	ensure.Nil(t, flagenv.ParseSet("RELL_", set))
+
+	ensure.DeepEqual(t, *addr, ":3")
+	ensure.DeepEqual(t, *adminPath, "env")
+	ensure.DeepEqual(t, *savedDir, "file")
+}
+
+func TestConfigRedacted(t *testing.T) {
+	t.Parallel()
+	secret, empty, admin := "secret", "", "topsecret"
+	config := &Config{
+		AdminPath:         &admin,
+		FbAppSecret:       &secret,
+		EmpCheckAppSecret: &empty,
+		Apps:              []rellenv.AppConfig{{ID: 1, Secret: "s"}, {ID: 2}},
+	}
+	redacted := config.Redacted()
+	ensure.DeepEqual(t, *redacted.AdminPath, "REDACTED")
+	ensure.DeepEqual(t, *redacted.FbAppSecret, "REDACTED")
+	ensure.DeepEqual(t, *redacted.EmpCheckAppSecret, "")
+	ensure.DeepEqual(t, redacted.Apps[0].Secret, "REDACTED")
+	ensure.DeepEqual(t, redacted.Apps[1].Secret, "")
+	ensure.DeepEqual(t, *config.FbAppSecret, "secret")
+	ensure.DeepEqual(t, *config.AdminPath, "topsecret")
+	ensure.DeepEqual(t, config.Apps[0].Secret, "s")
+}
+
+func TestConfigEmpCheck(t *testing.T) {
+	set := flag.NewFlagSet("rell", flag.ContinueOnError)
+	policy := set.String("empcheck", "graph", "")
+	uids := set.String("empcheck-uids", "", "")
+	domains := set.String("empcheck-domains", "", "")
+	header := set.String("empcheck-header", "X-Forwarded-Email", "")
+	config, err := LoadConfig(writeConfig(t, "rell.yaml",
+		"empcheck: uids\nempcheck-uids: [4, 1234]\nempcheck-domains: [a.com, b.com]\n"))
+	ensure.Nil(t, err)
+	ensure.Nil(t, config.Apply(set))
+
+	ids, err := parseUserIDs(*uids)
+	ensure.Nil(t, err)
+	checker, err := newEmpChecker(empCheckFlags{
+		Policy:    *policy,
+		UIDs:      ids,
+		Domains:   splitList(*domains),
+		Header:    *header,
+		AppSecret: "secret",
+	}, nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, checker, empcheck.UserIDs{4, 1234})
+	ensure.DeepEqual(t, splitList(*domains), []string{"a.com", "b.com"})
+
+	checker, err = newEmpChecker(
+		empCheckFlags{Policy: "header", Header: *header, TrustProxy: true}, nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, checker, &empcheck.Header{Name: "X-Forwarded-Email"})
+	checker, err = newEmpChecker(empCheckFlags{
+		Policy:     "header",
+		Domains:    []string{"a.com"},
+		Header:     *header,
+		TrustProxy: true,
+	}, nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, checker,
+		&empcheck.Header{Name: "X-Forwarded-Email", Domains: []string{"a.com"}})
+}
+
+func TestConfigEmpCheckInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := parseUserIDs("4,nope")
+	ensure.Err(t, err, regexp.MustCompile(`Invalid user id "nope"`))
+	for _, c := range []struct {
+		flags empCheckFlags
+		err   string
+	}{
+		{empCheckFlags{Policy: "uids", AppSecret: "s"}, "requires -empcheck-uids"},
+		{empCheckFlags{Policy: "uids", UIDs: empcheck.UserIDs{4}}, "requires -fb-app-secret"},
+		{empCheckFlags{Policy: "email-domain", AppSecret: "s"}, "requires -empcheck-domains"},
+		{
+			empCheckFlags{Policy: "email-domain", Domains: []string{"a.com"}},
+			"requires -fb-app-secret",
+		},
+		{
+			empCheckFlags{Policy: "header", Header: "X-Forwarded-Email"},
+			"requires -empcheck-trust-proxy",
+		},
+		// a domain allowlist does not stop clients from sending the header
+		{
+			empCheckFlags{Policy: "header", Header: "X-Forwarded-Email", Domains: []string{"a.com"}},
+			"requires -empcheck-trust-proxy",
+		},
+		{empCheckFlags{Policy: "ldap"}, "Unknown -empcheck"},
+	} {
+		_, err = newEmpChecker(c.flags, &empcheck.Checker{})
+		ensure.Err(t, err, regexp.MustCompile(c.err))
+	}
+}
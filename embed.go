@@ -0,0 +1,87 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/fbsamples/fbrell/examples"
+)
+
+// Copy of the public files, used when the directory is not available on disk.
+//
+//go:embed all:public
+var embeddedPublic embed.FS
+
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// The stock public files directory in a checkout.
+const stockPublicDir = "./public"
+
+// Returns the public files from dir, failing if it does not exist. Without a
+// dir the stock public files are used, from disk in a checkout or else the
+// embedded copy. The boolean is true if the embedded copy is used.
+func publicFileSystem(dir string) (http.FileSystem, bool, error) {
+	if dir != "" {
+		if !dirExists(dir) {
+			return nil, false, fmt.Errorf("Public directory %s does not exist", dir)
+		}
+		return http.Dir(dir), false, nil
+	}
+	if dirExists(stockPublicDir) {
+		return http.Dir(stockPublicDir), false, nil
+	}
+	sub, err := fs.Sub(embeddedPublic, "public")
+	if err != nil {
+		panic(err) // only fails for invalid paths
+	}
+	return http.FS(sub), true, nil
+}
+
+// The stock examples directory in a checkout.
+const stockExamplesDir = "./examples/db"
+
+// Returns the example sources for dirs, failing if any of them do not exist.
+// Without dirs the stock examples are used, from disk in a checkout or else
+// the embedded copy.
+func exampleSources(dirs []string) ([]examples.Source, error) {
+	if len(dirs) == 0 {
+		if dirExists(stockExamplesDir) {
+			return []examples.Source{examples.DirSource(stockExamplesDir)}, nil
+		}
+		return []examples.Source{examples.Embedded()}, nil
+	}
+	var sources []examples.Source
+	for _, dir := range dirs {
+		if !dirExists(dir) {
+			return nil, fmt.Errorf("Examples directory %s does not exist", dir)
+		}
+		sources = append(sources, examples.DirSource(dir))
+	}
+	return sources, nil
+}
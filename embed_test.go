@@ -0,0 +1,62 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+)
+
+func TestExampleSources(t *testing.T) {
+	t.Parallel()
+	sources, err := exampleSources(nil)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(sources), 1)
+	ensure.DeepEqual(t, sources[0].Dir, stockExamplesDir)
+
+	team := t.TempDir()
+	sources, err = exampleSources([]string{stockExamplesDir, team})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sources[1].Dir, team)
+
+	missing := filepath.Join(team, "missing")
+	_, err = exampleSources([]string{stockExamplesDir, missing})
+	ensure.Err(t, err, regexp.MustCompile(regexp.QuoteMeta(missing)+" does not exist"))
+}
+
+func TestPublicFileSystem(t *testing.T) {
+	t.Parallel()
+	_, embedded, err := publicFileSystem("")
+	ensure.Nil(t, err)
+	ensure.False(t, embedded)
+
+	dir := t.TempDir()
+	_, embedded, err = publicFileSystem(dir)
+	ensure.Nil(t, err)
+	ensure.False(t, embedded)
+
+	missing := filepath.Join(dir, "missing")
+	_, _, err = publicFileSystem(missing)
+	ensure.Err(t, err, regexp.MustCompile(regexp.QuoteMeta(missing)+" does not exist"))
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+)
+
+//go:generate go run gen_embedded.go
+
+// EmbeddedName is the Source name of the embedded examples.
+const EmbeddedName = "embedded"
+
+// Archive of the db directory, see gen_embedded.go.
+//
+//go:embed db.zip
+var embeddedDB []byte
+
+// Embedded returns a Source for the stock examples built into the binary.
+func Embedded() Source {
+	zr, err := zip.NewReader(bytes.NewReader(embeddedDB), int64(len(embeddedDB)))
+	if err != nil {
+		panic(err) // db.zip is generated, so this is a build problem
+	}
+	return Source{Name: EmbeddedName, FS: zr}
+}
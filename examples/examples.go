@@ -22,21 +22,37 @@
 package examples
 
 import (
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 
 	"github.com/fbsamples/fbrell/errcode"
 )
 
-// Some categories are hidden from the listing.
-var hidden = map[string]bool{
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Name of the optional manifest file in a category directory. It uses the
+// same "key: value" format as the example front-matter, and supports the
+// title, icon, hidden, order and lint keys.
+const manifestName = "_category"
+
+// Categories which are hidden from the listing unless a manifest says
+// "hidden: false". These were hidden by name before manifests existed.
+var hiddenByDefault = map[string]bool{
 	"auth":   true,
 	"bugs":   true,
 	"fb.api": true,
@@ -49,8 +65,20 @@ var hidden = map[string]bool{
 	"saved":  true,
 }
 
+// Store provides the examples DB along with saved examples. The DB may be
+// replaced at runtime, see Swap and Watcher.
 type Store struct {
-	DB *DB
+	Saved Backend
+
+	current atomic.Pointer[DB]
+}
+
+// NewStore returns a Store serving the given DB and saved examples, which
+// may be nil to disable saving.
+func NewStore(db *DB, saved Backend) *Store {
+	s := &Store{Saved: saved}
+	s.current.Store(db)
+	return s
 }
 
 type Example struct {
@@ -59,102 +87,282 @@ type Example struct {
 	AutoRun bool   `json:"autoRun"`
 	Title   string `json:"-"`
 	URL     string `json:"-"`
+	Meta    Meta   `json:"-"`
+	ID      string `json:"-"` // ContentID of the Content
+	Root    string `json:"-"` // examples directory the example was loaded from
+
+	// Template is the parsed Content, and is nil for saved examples which are
+	// not valid templates. See Render.
+	Template *template.Template `json:"-"`
+
+	// the template parse error, only kept by MakeLintDBFrom
+	templateErr error
+}
+
+// DisplayName returns the front-matter title if there is one, or the name.
+func (e *Example) DisplayName() string {
+	if e.Meta.Title != "" {
+		return e.Meta.Title
+	}
+	return e.Name
 }
 
 type Category struct {
 	Name    string
 	Example []*Example
 	Hidden  bool
+	Title   string // display name, defaults to Name
+	Icon    string
+	Order   int
+
+	// SkipLint is set by "lint: false" in the manifest, for legacy examples
+	// which are kept as they are.
+	SkipLint bool
+}
+
+// DisplayName returns the manifest title if there is one, or the name.
+func (c *Category) DisplayName() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.Name
 }
 
 type DB struct {
 	Category map[string]*Category
 	Reverse  map[string]*Example
+
+	// Categories are sorted by their order and then their name. The examples
+	// in each category are sorted the same way.
+	Categories []*Category
+
+	// Roots are the directories the DB was loaded from, in order of
+	// increasing precedence.
+	Roots []string
+
+	Index *Index
 }
 
+// Category under which user saved examples are served.
+const savedCategory = "saved"
+
+// Maximum size in bytes of saved example content.
+const MaxSavedSize = 256 * 1024
+
+var savedIDRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
 var (
 	// Stock response for the index page.
-	emptyExample = &Example{Title: "Welcome", URL: "/", AutoRun: true}
+	emptyExample = &Example{Title: "Welcome", URL: "/", AutoRun: true, ID: ContentID("")}
 	classExample = &url.URL{Path: "classes/Example"}
 )
 
-func MustMakeDB(dir string) *DB {
-	db, err := MakeDB(dir)
+func MustMakeDB(dirs ...string) *DB {
+	db, err := MakeDB(dirs...)
 	if err != nil {
 		log.Fatal(err)
 	}
 	return db
 }
 
-// Loads a specific examples directory.
-func MakeDB(dir string) (*DB, error) {
+// Source is a tree of example files, with a directory per category.
+type Source struct {
+	Name string // shown as the Root of its examples
+	FS   fs.FS
+	Dir  string // the directory FS reads from, empty if not on disk
+}
+
+// DirSource returns a Source for an examples directory on disk.
+func DirSource(dir string) Source {
+	return Source{Name: dir, FS: os.DirFS(dir), Dir: dir}
+}
+
+// Loads the given examples directories. See MakeDBFrom.
+func MakeDB(dirs ...string) (*DB, error) {
+	var sources []Source
+	for _, dir := range dirs {
+		sources = append(sources, DirSource(dir))
+	}
+	return MakeDBFrom(sources...)
+}
+
+// Loads the given example sources. Later sources take precedence, an example
+// replaces one with the same category and name from an earlier source, and
+// manifest keys override those set by earlier manifests.
+func MakeDBFrom(sources ...Source) (*DB, error) {
+	return makeDB(false, sources)
+}
+
+// MakeLintDBFrom loads the example sources like MakeDBFrom, but keeps the
+// examples whose templates do not parse so Lint can report each of them.
+// The DB should not be used to render examples.
+func MakeLintDBFrom(sources ...Source) (*DB, error) {
+	return makeDB(true, sources)
+}
+
+func makeDB(lint bool, sources []Source) (*DB, error) {
 	db := &DB{
 		Category: make(map[string]*Category),
 		Reverse:  make(map[string]*Example),
 	}
-	db.Reverse[ContentID(emptyExample.Content)] = emptyExample
+	db.Reverse[emptyExample.ID] = emptyExample
 
-	err := filepath.Walk(
-		dir,
-		func(exampleFile string, info os.FileInfo, err error) error {
+	for _, source := range sources {
+		db.Roots = append(db.Roots, source.Name)
+		if err := db.load(source, lint); err != nil {
+			return nil, err
+		}
+	}
+	for _, category := range db.Category {
+		sort.SliceStable(category.Example, func(i, j int) bool {
+			a, b := category.Example[i], category.Example[j]
+			if a.Meta.Order != b.Meta.Order {
+				return a.Meta.Order < b.Meta.Order
+			}
+			return a.Name < b.Name
+		})
+		db.Categories = append(db.Categories, category)
+	}
+	sort.Slice(db.Categories, func(i, j int) bool {
+		a, b := db.Categories[i], db.Categories[j]
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.Name < b.Name
+	})
+	db.Index = NewIndex(db)
+	return db, nil
+}
+
+// Loads the examples in source on top of those already in the DB. If lint is
+// set, examples with invalid templates are kept along with the error.
+func (d *DB) load(source Source, lint bool) error {
+	return fs.WalkDir(
+		source.FS,
+		".",
+		func(p string, entry fs.DirEntry, err error) error {
+			exampleFile := filepath.Join(source.Name, filepath.FromSlash(p))
 			if err != nil {
-				return err
+				return fmt.Errorf("Failed to read examples in %s: %s", exampleFile, err)
 			}
-			if info.IsDir() {
+			if entry.IsDir() {
 				return nil
 			}
-			categoryName := filepath.Base(filepath.Dir(exampleFile))
-			exampleName := filepath.Base(exampleFile)
+			exampleName := path.Base(p)
+			// skip editor swap and backup files
+			if exampleName != manifestName && path.Ext(exampleName) != ".html" {
+				return nil
+			}
+			categoryName := path.Base(path.Dir(p))
+			if categoryName == "." {
+				categoryName = filepath.Base(source.Name)
+			}
 
-			category := db.Category[categoryName]
+			category := d.Category[categoryName]
 			if category == nil {
 				category = &Category{
 					Name:   categoryName,
-					Hidden: hidden[categoryName],
+					Hidden: hiddenByDefault[categoryName],
 				}
-				db.Category[categoryName] = category
+				d.Category[categoryName] = category
 			}
 
-			contentBytes, err := os.ReadFile(exampleFile)
+			if exampleName == manifestName {
+				contentBytes, err := fs.ReadFile(source.FS, p)
+				if err != nil {
+					return fmt.Errorf("Failed to read manifest %s: %s", exampleFile, err)
+				}
+				if err := parseManifest(string(contentBytes), category); err != nil {
+					return fmt.Errorf("Invalid manifest %s: %s", exampleFile, err)
+				}
+				return nil
+			}
+
+			contentBytes, err := fs.ReadFile(source.FS, p)
 			if err != nil {
 				return fmt.Errorf("Failed to read example %s: %s", exampleFile, err)
 			}
 
+			meta, content, err := parseFrontMatter(string(contentBytes))
+			if err != nil {
+				return fmt.Errorf("Invalid example %s: %s", exampleFile, err)
+			}
+
 			autoRun := true
-			if categoryName == "saved" {
+			if categoryName == savedCategory {
 				autoRun = false
 			}
+			if meta.AutoRun != nil {
+				autoRun = *meta.AutoRun
+			}
 
-			content := string(contentBytes)
 			cleanName := exampleName[:len(exampleName)-5] // drop .html
 			example := &Example{
 				Name:    cleanName,
 				Content: content,
 				AutoRun: autoRun,
-				Title:   categoryName + " · " + cleanName,
 				URL:     path.Join("/", categoryName, cleanName),
+				Meta:    meta,
+				Root:    source.Name,
+			}
+			example.Title = categoryName + " · " + example.DisplayName()
+			example.ID = ContentID(strings.TrimSpace(content))
+			example.Template, err = parseTemplate(example.URL, content)
+			if err != nil && lint {
+				example.templateErr = err
+			} else if err != nil {
+				return fmt.Errorf("Invalid template in example %s: %s", exampleFile, err)
+			}
+			if replaced := category.add(example); replaced != nil && d.Reverse[replaced.ID] == replaced {
+				delete(d.Reverse, replaced.ID)
 			}
-			category.Example = append(category.Example, example)
-			db.Reverse[ContentID(strings.TrimSpace(content))] = example
+			d.Reverse[example.ID] = example
 			return nil
 		},
 	)
-	if err != nil {
-		return nil, err
+}
+
+// Adds an example, replacing and returning an existing one with the same name.
+func (c *Category) add(example *Example) *Example {
+	for i, existing := range c.Example {
+		if existing.Name == example.Name {
+			c.Example[i] = example
+			return existing
+		}
 	}
-	return db, nil
+	c.Example = append(c.Example, example)
+	return nil
 }
 
-// Load an Example for a given version and path.
+// Snapshot returns the current DB. Callers should hold on to the returned DB
+// for the duration of a request to get a consistent view.
+func (s *Store) Snapshot() *DB {
+	return s.current.Load()
+}
+
+// Swap atomically replaces the current DB.
+func (s *Store) Swap(db *DB) {
+	s.current.Store(db)
+}
+
+// Load an Example for a given path from the current DB.
 func (s *Store) Load(path string) (*Example, error) {
+	return s.LoadFrom(s.Snapshot(), path)
+}
+
+// LoadFrom loads an Example for a given path from the given DB snapshot.
+func (s *Store) LoadFrom(db *DB, path string) (*Example, error) {
 	parts := strings.Split(path, "/")
 	if len(parts) == 2 && parts[1] == "" {
 		return emptyExample, nil
 	} else if len(parts) != 3 {
 		return nil, errcode.New(http.StatusNotFound, "Invalid URL: %s", path)
 	}
+	if parts[1] == savedCategory {
+		return s.loadSaved(parts[2])
+	}
 
-	category := s.DB.FindCategory(parts[1])
+	category := db.FindCategory(parts[1])
 	if category == nil {
 		return nil, errcode.New(http.StatusNotFound, "Could not find category: %s", parts[1])
 	}
@@ -165,6 +373,69 @@ func (s *Store) Load(path string) (*Example, error) {
 	return example, nil
 }
 
+// Save stores the given content and returns the Example it is reachable as.
+// Content matching a stock example returns the stock example instead.
+func (s *Store) Save(content string) (*Example, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errcode.New(http.StatusBadRequest, "Nothing to save.")
+	}
+	if len(content) > MaxSavedSize {
+		return nil, errcode.New(http.StatusRequestEntityTooLarge,
+			"Example is too large to save: %d bytes", len(content))
+	}
+	id := ContentID(content)
+	if example, ok := s.Snapshot().Reverse[id]; ok {
+		return example, nil
+	}
+	if s.Saved == nil {
+		return nil, errcode.New(http.StatusNotImplemented, "Saving examples is not enabled.")
+	}
+	if err := s.Saved.Put(id, content); err != nil {
+		if errors.Is(err, ErrSavedFull) {
+			return nil, errcode.New(http.StatusInsufficientStorage,
+				"No room left to save examples.")
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return nil, errcode.New(http.StatusConflict,
+				"A different example is already saved as %s.", id)
+		}
+		return nil, fmt.Errorf("Failed to save example %s: %s", id, err)
+	}
+	return savedExample(id, content), nil
+}
+
+func (s *Store) loadSaved(id string) (*Example, error) {
+	if s.Saved == nil || !savedIDRegexp.MatchString(id) {
+		return nil, errcode.New(http.StatusNotFound, "Could not find saved example: %s", id)
+	}
+	content, err := s.Saved.Get(id)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errcode.New(http.StatusNotFound, "Could not find saved example: %s", id)
+		}
+		return nil, fmt.Errorf("Failed to load saved example %s: %s", id, err)
+	}
+	return savedExample(id, content), nil
+}
+
+func savedExample(id, content string) *Example {
+	example := &Example{
+		Name:    id,
+		Content: content,
+		AutoRun: false,
+		Title:   savedCategory + " · " + id,
+		URL:     path.Join("/", savedCategory, id),
+		ID:      id,
+	}
+	// saved examples are often snippets that aren't valid templates, those
+	// are rendered as is.
+	if tpl, err := parseTemplate(example.URL, content); err == nil {
+		example.Template = tpl
+	}
+	return example
+}
+
 // Find a category by it's name.
 func (d *DB) FindCategory(name string) *Category {
 	for _, category := range d.Category {
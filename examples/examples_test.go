@@ -0,0 +1,460 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/fbsamples/fbrell/examples"
+)
+
+func testStore(t *testing.T) *examples.Store {
+	return examples.NewStore(
+		examples.MustMakeDB("db"), &examples.DiskBackend{Dir: t.TempDir()})
+}
+
+func errCode(t *testing.T, err error) int {
+	code, ok := err.(interface{ Code() int })
+	if !ok {
+		t.Fatalf("was expecting an ErrorCode but got: %v", err)
+	}
+	return code.Code()
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Parallel()
+	store := testStore(t)
+	saved, err := store.Save("  <h1>hello</h1>\n")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, saved.URL, "/saved/"+examples.ContentID("<h1>hello</h1>"))
+	ensure.False(t, saved.AutoRun)
+
+	loaded, err := store.Load(saved.URL)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, loaded.Content, "<h1>hello</h1>")
+}
+
+func TestSaveStockExample(t *testing.T) {
+	t.Parallel()
+	store := testStore(t)
+	stock, err := store.Load("/Sharing/2 - FB.ui Dialogs")
+	ensure.Nil(t, err)
+	saved, err := store.Save(stock.Content)
+	ensure.Nil(t, err)
+	ensure.True(t, saved == stock)
+}
+
+func TestSaveEmpty(t *testing.T) {
+	t.Parallel()
+	_, err := testStore(t).Save(" \n ")
+	ensure.DeepEqual(t, errCode(t, err), http.StatusBadRequest)
+}
+
+func TestLoadSavedMissing(t *testing.T) {
+	t.Parallel()
+	store := testStore(t)
+	_, err := store.Load("/saved/" + examples.ContentID("missing"))
+	ensure.DeepEqual(t, errCode(t, err), http.StatusNotFound)
+	_, err = store.Load("/saved/../../etc/passwd")
+	ensure.DeepEqual(t, errCode(t, err), http.StatusNotFound)
+	_, err = store.Load("/saved/nothex")
+	ensure.DeepEqual(t, errCode(t, err), http.StatusNotFound)
+}
+
+func TestSavedNeverOverwritten(t *testing.T) {
+	t.Parallel()
+	backend := &examples.DiskBackend{Dir: t.TempDir()}
+	id := examples.ContentID("one")
+	ensure.Nil(t, backend.Put(id, "one"))
+	ensure.Nil(t, backend.Put(id, "one"))
+	err := backend.Put(id, "two")
+	ensure.True(t, errors.Is(err, fs.ErrExist))
+	content, err := backend.Get(id)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, content, "one")
+
+	store := examples.NewStore(examples.MustMakeDB("db"), backend)
+	ensure.Nil(t, backend.Put(examples.ContentID("three"), "not three"))
+	_, err = store.Save("three")
+	ensure.DeepEqual(t, errCode(t, err), http.StatusConflict)
+}
+
+func TestSavedMaxBytes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, examples.ContentID("old")+".html"),
+		[]byte("old"), 0644))
+	store := examples.NewStore(
+		examples.MustMakeDB("db"), &examples.DiskBackend{Dir: dir, MaxBytes: 8})
+	_, err := store.Save("12345")
+	ensure.Nil(t, err)
+	_, err = store.Save("6")
+	ensure.DeepEqual(t, errCode(t, err), http.StatusInsufficientStorage)
+	_, err = store.Save("12345")
+	ensure.Nil(t, err)
+}
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Printf(format string, v ...interface{}) {
+	l.t.Logf(format, v...)
+}
+
+func TestWatcherReload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ensure.Nil(t, os.Mkdir(filepath.Join(dir, "cat"), 0755))
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "cat", "one.html"), []byte("one"), 0644))
+
+	store := examples.NewStore(examples.MustMakeDB(dir), nil)
+	before := store.Snapshot()
+	watcher := &examples.Watcher{
+		Store:   store,
+		Sources: []examples.Source{examples.DirSource(dir)},
+		Logger:  testLogger{t},
+		Delay:   10 * time.Millisecond,
+	}
+	ensure.Nil(t, watcher.Start())
+	defer watcher.Close()
+
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "cat", "two.html"), []byte("two"), 0644))
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Snapshot() == before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	example, err := store.Load("/cat/two")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, example.Content, "two")
+	ensure.DeepEqual(t, len(before.FindCategory("cat").Example), 1)
+}
+
+func writeExample(t *testing.T, dir, category, name, content string) {
+	ensure.Nil(t, os.MkdirAll(filepath.Join(dir, category), 0755))
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, category, name+".html"), []byte(content), 0644))
+}
+
+func TestFrontMatter(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "cat", "one", `---
+title: The One
+description: First of its kind.
+# comments are ignored
+tags: a, b,
+autorun: false
+permissions: email
+min-version: v18.0
+---
+<b>one</b>`)
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+	example := db.FindCategory("cat").FindExample("one")
+	ensure.DeepEqual(t, example.Content, "<b>one</b>")
+	ensure.DeepEqual(t, example.Title, "cat · The One")
+	ensure.DeepEqual(t, example.DisplayName(), "The One")
+	ensure.False(t, example.AutoRun)
+	ensure.DeepEqual(t, example.Meta.Description, "First of its kind.")
+	ensure.DeepEqual(t, example.Meta.Tags, []string{"a", "b"})
+	ensure.DeepEqual(t, example.Meta.Permissions, []string{"email"})
+	ensure.DeepEqual(t, example.Meta.MinVersion, "v18.0")
+	ensure.True(t, db.Reverse[examples.ContentID("<b>one</b>")] == example)
+}
+
+func TestFrontMatterInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"unknown key":   "---\ncolor: red\n---\n",
+		"bad autorun":   "---\nautorun: maybe\n---\n",
+		"missing colon": "---\ntitle\n---\n",
+		"unterminated":  "---\ntitle: x\n",
+	}
+	for name, content := range cases {
+		dir := t.TempDir()
+		writeExample(t, dir, "cat", "bad", content)
+		_, err := examples.MakeDB(dir)
+		ensure.NotNil(t, err, name)
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "b", "x", "---\norder: 2\n---\nx")
+	writeExample(t, dir, "b", "y", "---\norder: 1\n---\ny")
+	writeExample(t, dir, "b", "a", "a")
+	writeExample(t, dir, "a", "z", "z")
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "b", "_category"),
+		[]byte("title: Bee\nicon: B\norder: -1\n"), 0644))
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "a", "_category"),
+		[]byte("hidden: true\n"), 0644))
+
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(db.Categories), 2)
+	b, a := db.Categories[0], db.Categories[1]
+	ensure.DeepEqual(t, b.DisplayName(), "Bee")
+	ensure.DeepEqual(t, b.Icon, "B")
+	ensure.False(t, b.Hidden)
+	ensure.True(t, a.Hidden)
+	var names []string
+	for _, example := range b.Example {
+		names = append(names, example.Name)
+	}
+	ensure.DeepEqual(t, names, []string{"a", "y", "x"})
+}
+
+func TestHiddenByDefault(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "secret", "a", "a")
+	writeExample(t, dir, "bugs", "b", "b")
+	writeExample(t, dir, "tests", "c", "c")
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "tests", "_category"),
+		[]byte("hidden: false\n"), 0644))
+
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+	ensure.True(t, db.FindCategory("secret").Hidden)
+	ensure.True(t, db.FindCategory("bugs").Hidden)
+	ensure.False(t, db.FindCategory("tests").Hidden)
+}
+
+func TestManifestInvalid(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "a", "z", "z")
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "a", "_category"),
+		[]byte("hidden: sometimes\n"), 0644))
+	_, err := examples.MakeDB(dir)
+	ensure.Err(t, err, regexp.MustCompile(`invalid hidden`))
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "login", "basic", "---\ntitle: Basic Login\ntags: auth\n---\n"+
+		"<p>Click to log in.</p><script>FB.login(function(r) { Log.info(r) })</script>")
+	writeExample(t, dir, "graph", "me", "<script>FB.api('/me', Log.info)</script> no login here")
+	writeExample(t, dir, "secret", "internal", "<script>FB.login()</script>")
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "secret", "_category"),
+		[]byte("hidden: true\n"), 0644))
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+
+	results := db.Index.Search("login", false)
+	ensure.DeepEqual(t, len(results), 2)
+	ensure.DeepEqual(t, results[0].Example.Name, "basic")
+	ensure.DeepEqual(t, results[0].Calls, []string{"FB.login"})
+	ensure.DeepEqual(t, results[1].Example.Name, "me")
+
+	results = db.Index.Search("FB.login(", true)
+	ensure.DeepEqual(t, len(results), 2)
+
+	results = db.Index.Search("fb.api LOGIN", false)
+	ensure.DeepEqual(t, len(results), 1)
+	ensure.DeepEqual(t, results[0].Example.Name, "me")
+	var marked []string
+	for _, fragment := range results[0].Snippet {
+		if fragment.Match {
+			marked = append(marked, fragment.Text)
+		}
+	}
+	ensure.DeepEqual(t, marked, []string{"FB.api", "login"})
+
+	ensure.DeepEqual(t, len(db.Index.Search("  ", true)), 0)
+	ensure.DeepEqual(t, len(db.Index.Search("nomatch", true)), 0)
+}
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "cat", "tpl", `<div data-app="{{.AppID}}">{{.Version}} {{.Locale}}</div>`)
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+
+	var buf strings.Builder
+	example := db.FindCategory("cat").FindExample("tpl")
+	ensure.Nil(t, example.Render(&buf, &examples.TemplateData{
+		AppID:   "42",
+		Version: "v1.0",
+		Locale:  "fr_FR",
+	}))
+	ensure.DeepEqual(t, buf.String(), `<div data-app="42">v1.0 fr_FR</div>`)
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"parse":   "{{.Rand",
+		"execute": "{{.NoSuchField}}",
+	}
+	for name, content := range cases {
+		dir := t.TempDir()
+		writeExample(t, dir, "cat", "bad", content)
+		_, err := examples.MakeDB(dir)
+		ensure.Err(t, err, regexp.MustCompile(`Invalid template`), name)
+	}
+}
+
+func TestSavedInvalidTemplate(t *testing.T) {
+	t.Parallel()
+	store := testStore(t)
+	saved, err := store.Save("{{ not a template")
+	ensure.Nil(t, err)
+	loaded, err := store.Load(saved.URL)
+	ensure.Nil(t, err)
+	var buf strings.Builder
+	ensure.Nil(t, loaded.Render(&buf, &examples.TemplateData{}))
+	ensure.DeepEqual(t, buf.String(), "{{ not a template")
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "cat", "good", `<fb:like></fb:like><script>FB.api('/me', Log.info.bind('me'))</script>`)
+	writeExample(t, dir, "cat", "copy", `<fb:like></fb:like><script>FB.api('/me', Log.info.bind('me'))</script>`)
+	writeExample(t, dir, "cat", "tags", `<fb:profile-pic></fb:profile-pic><div class="fb-page fb-bogus"></div>`)
+	writeExample(t, dir, "cat", "calls", `<script>FB.getSession(); FB.Data.query('x'); Log.info('ok')</script>`)
+	writeExample(t, dir, "cat", "quiet", `<script>FB.login()</script>`)
+	db, err := examples.MakeDB(dir)
+	ensure.Nil(t, err)
+
+	found := map[string][]string{}
+	for _, issue := range examples.Lint(db) {
+		found[issue.Example] = append(found[issue.Example], issue.Check+": "+issue.Message)
+	}
+	ensure.DeepEqual(t, found, map[string][]string{
+		"/cat/good": {"duplicate: same content as /cat/copy"},
+		"/cat/tags": {
+			"unknown-tag: unknown plugin fb:profile-pic",
+			"unknown-tag: unknown plugin fb-bogus",
+		},
+		"/cat/calls": {
+			"deprecated-call: FB.Data.query is deprecated, use FB.api",
+			"deprecated-call: FB.getSession is deprecated, use FB.getAuthResponse",
+		},
+		"/cat/quiet": {"missing-log: script never logs via Log.*"},
+	})
+}
+
+func TestLintTemplates(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "cat", "broken", "{{ not a template")
+	writeExample(t, dir, "cat", "unclosed", "{{ if .Env }}")
+	writeExample(t, dir, "cat", "clean", "<div>clean</div>")
+	_, err := examples.MakeDBFrom(examples.DirSource(dir))
+	ensure.NotNil(t, err)
+
+	db, err := examples.MakeLintDBFrom(examples.DirSource(dir))
+	ensure.Nil(t, err)
+	found := map[string][]string{}
+	for _, issue := range examples.Lint(db) {
+		found[issue.Example] = append(found[issue.Example], issue.Check)
+	}
+	ensure.DeepEqual(t, found, map[string][]string{
+		"/cat/broken":   {examples.LintTemplate},
+		"/cat/unclosed": {examples.LintTemplate},
+	})
+}
+
+func TestLintSkipped(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeExample(t, dir, "legacy", "old", `<fb:profile-pic></fb:profile-pic>`)
+	writeExample(t, dir, "legacy", "broken", "{{ not a template")
+	writeExample(t, dir, "new", "copy", `<fb:profile-pic></fb:profile-pic>`)
+	ensure.Nil(t, os.WriteFile(filepath.Join(dir, "legacy", "_category"),
+		[]byte("lint: false\n"), 0644))
+	db, err := examples.MakeLintDBFrom(examples.DirSource(dir))
+	ensure.Nil(t, err)
+	found := map[string][]string{}
+	for _, issue := range examples.Lint(db) {
+		found[issue.Example] = append(found[issue.Example], issue.Check)
+	}
+	ensure.DeepEqual(t, found, map[string][]string{
+		"/legacy/broken": {examples.LintTemplate},
+		"/new/copy":      {examples.LintDuplicate, examples.LintUnknownTag},
+	})
+}
+
+func TestOverlayRoots(t *testing.T) {
+	t.Parallel()
+	base, team := t.TempDir(), t.TempDir()
+	writeExample(t, base, "cat", "shared", "base shared")
+	writeExample(t, base, "cat", "base-only", "base only")
+	writeExample(t, team, "cat", "shared", "team shared")
+	writeExample(t, team, "team", "private", "team private")
+	ensure.Nil(t, os.WriteFile(filepath.Join(base, "cat", "_category"), []byte("title: Base\nicon: B\n"), 0644))
+	ensure.Nil(t, os.WriteFile(filepath.Join(team, "cat", "_category"), []byte("title: Team\n"), 0644))
+
+	db, err := examples.MakeDB(base, team)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, db.Roots, []string{base, team})
+
+	cat := db.FindCategory("cat")
+	ensure.DeepEqual(t, cat.Title, "Team")
+	ensure.DeepEqual(t, cat.Icon, "B")
+	ensure.DeepEqual(t, len(cat.Example), 2)
+
+	shared := cat.FindExample("shared")
+	ensure.DeepEqual(t, shared.Content, "team shared")
+	ensure.DeepEqual(t, shared.Root, team)
+	ensure.DeepEqual(t, cat.FindExample("base-only").Root, base)
+	ensure.DeepEqual(t, db.FindCategory("team").FindExample("private").Root, team)
+
+	_, ok := db.Reverse[examples.ContentID("base shared")]
+	ensure.False(t, ok)
+	ensure.DeepEqual(t, db.Reverse[examples.ContentID("team shared")], shared)
+}
+
+// db.zip must be regenerated with go generate when the stock examples change.
+func TestEmbeddedUpToDate(t *testing.T) {
+	t.Parallel()
+	contents := func(db *examples.DB) map[string]interface{} {
+		m := map[string]interface{}{}
+		for _, category := range db.Categories {
+			m[category.Name] = []interface{}{
+				category.Title, category.Icon, category.Hidden, category.Order,
+			}
+			for _, example := range category.Example {
+				m[example.URL] = example.ID
+			}
+		}
+		return m
+	}
+	disk, err := examples.MakeDB("db")
+	ensure.Nil(t, err)
+	embedded, err := examples.MakeDBFrom(examples.Embedded())
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, embedded.Roots, []string{examples.EmbeddedName})
+	ensure.DeepEqual(t, contents(embedded), contents(disk))
+}
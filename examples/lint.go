@@ -0,0 +1,174 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Names of the checks performed by Lint.
+const (
+	LintLoad        = "load"
+	LintTemplate    = "template"
+	LintDuplicate   = "duplicate"
+	LintUnknownTag  = "unknown-tag"
+	LintDeprecated  = "deprecated-call"
+	LintMissingLogs = "missing-log"
+)
+
+// Plugins currently supported by the JS SDK, usable either as <fb:name> tags
+// or as elements with a fb-name class.
+var knownPlugins = map[string]bool{
+	"comment-embed":      true,
+	"comments":           true,
+	"comments-count":     true,
+	"group":              true,
+	"like":               true,
+	"login-button":       true,
+	"page":               true,
+	"post":               true,
+	"quote":              true,
+	"save":               true,
+	"send":               true,
+	"share-button":       true,
+	"video":              true,
+	"xfbml-parse-ignore": true,
+}
+
+// SDK calls which have been deprecated, and what to use instead if anything.
+// Entries ending in a dot match everything under them.
+var deprecatedCalls = map[string]string{
+	"FB.getSession":           "FB.getAuthResponse",
+	"FB.Canvas.setAutoResize": "FB.Canvas.setAutoGrow",
+	"FB.Canvas.Prefetcher.":   "",
+	"FB.Connect.":             "FB.login and FB.getLoginStatus",
+	"FB.Data.":                "FB.api",
+	"FB.Insights.":            "",
+}
+
+var (
+	xfbmlTagRegexp  = regexp.MustCompile(`<fb:([A-Za-z0-9_-]+)`)
+	classAttrRegexp = regexp.MustCompile(`\bclass\s*=\s*["']([^"']*)["']`)
+	scriptRegexp    = regexp.MustCompile(`(?i)<script\b`)
+	logCallRegexp   = regexp.MustCompile(`\bLog\.[A-Za-z_]+`)
+)
+
+// LintIssue is a problem found in an example.
+type LintIssue struct {
+	Example string `json:"example,omitempty"` // URL of the example
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Lint checks the examples in the DB for common mistakes: templates that do
+// not parse (when loaded with MakeLintDBFrom), duplicate content, unknown
+// plugin tags, deprecated SDK calls and scripts which never log their
+// results. Categories whose manifest has "lint: false" are only checked for
+// templates that do not parse.
+func Lint(db *DB) []*LintIssue {
+	var issues []*LintIssue
+	add := func(e *Example, check, format string, args ...interface{}) {
+		issues = append(issues, &LintIssue{
+			Example: e.URL,
+			Check:   check,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	seen := make(map[string]*Example)
+	for _, category := range db.Categories {
+		for _, e := range category.Example {
+			if e.templateErr != nil {
+				add(e, LintTemplate, "%s", e.templateErr)
+			}
+
+			if category.SkipLint {
+				if _, ok := seen[e.ID]; !ok {
+					seen[e.ID] = e
+				}
+				continue
+			}
+
+			if first, ok := seen[e.ID]; ok {
+				add(e, LintDuplicate, "same content as %s", first.URL)
+			} else {
+				seen[e.ID] = e
+			}
+
+			for _, tag := range pluginTags(e.Content) {
+				if !knownPlugins[strings.TrimPrefix(strings.TrimPrefix(tag, "fb:"), "fb-")] {
+					add(e, LintUnknownTag, "unknown plugin %s", tag)
+				}
+			}
+
+			for _, call := range uniqueCalls(e.Content) {
+				replacement, ok := deprecatedCall(call)
+				switch {
+				case !ok:
+				case replacement == "":
+					add(e, LintDeprecated, "%s has been removed", call)
+				default:
+					add(e, LintDeprecated, "%s is deprecated, use %s", call, replacement)
+				}
+			}
+
+			if scriptRegexp.MatchString(e.Content) && !logCallRegexp.MatchString(e.Content) {
+				add(e, LintMissingLogs, "script never logs via Log.*")
+			}
+		}
+	}
+	return issues
+}
+
+// Returns the plugin tags and classes used in the content, in order of first
+// use, as "fb:name" or "fb-name".
+func pluginTags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, m := range xfbmlTagRegexp.FindAllStringSubmatch(content, -1) {
+		add("fb:" + strings.ToLower(m[1]))
+	}
+	for _, m := range classAttrRegexp.FindAllStringSubmatch(content, -1) {
+		for _, class := range strings.Fields(m[1]) {
+			if strings.HasPrefix(class, "fb-") {
+				add(class)
+			}
+		}
+	}
+	return tags
+}
+
+func deprecatedCall(call string) (string, bool) {
+	for name, replacement := range deprecatedCalls {
+		if call == name || strings.HasSuffix(name, ".") && strings.HasPrefix(call, name) {
+			return replacement, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const frontMatterDelim = "---"
+
+// Meta holds the optional front-matter metadata of an Example. Example files
+// may start with a block of "key: value" lines between two "---" lines:
+//
+//	---
+//	title: Basic Login
+//	description: The bare minimum to get Facebook Login working.
+//	tags: login, auth
+//	autorun: false
+//	permissions: email, public_profile
+//	min-version: v18.0
+//	order: 10
+//	---
+//
+// List values are comma separated. Blank lines and lines starting with # are
+// ignored. The block is removed from the Example Content.
+type Meta struct {
+	Title       string   // human readable title, defaults to the file name
+	Description string   // a sentence or two about the example
+	Tags        []string // free form tags
+	AutoRun     *bool    // overrides the category default when set
+	Permissions []string // login permissions the example needs
+	MinVersion  string   // minimum Graph API / SDK version, like v18.0
+	Order       int      // position within the category, see DB.Categories
+}
+
+// Splits the front-matter from the content if present.
+func parseFrontMatter(content string) (Meta, string, error) {
+	var meta Meta
+	first, rest, _ := strings.Cut(content, "\n")
+	if strings.TrimSpace(first) != frontMatterDelim {
+		return meta, content, nil
+	}
+	var block strings.Builder
+	for {
+		line, remaining, found := strings.Cut(rest, "\n")
+		if strings.TrimSpace(line) == frontMatterDelim {
+			rest = remaining
+			break
+		}
+		if !found {
+			return meta, "", fmt.Errorf("front-matter is missing closing %q", frontMatterDelim)
+		}
+		block.WriteString(line + "\n")
+		rest = remaining
+	}
+	err := parseFields(block.String(), 2, func(key, value string) error {
+		var err error
+		switch key {
+		case "title":
+			meta.Title = value
+		case "description":
+			meta.Description = value
+		case "tags":
+			meta.Tags = splitList(value)
+		case "autorun":
+			var autoRun bool
+			autoRun, err = strconv.ParseBool(value)
+			meta.AutoRun = &autoRun
+		case "permissions":
+			meta.Permissions = splitList(value)
+		case "min-version":
+			meta.MinVersion = value
+		case "order":
+			meta.Order, err = strconv.Atoi(value)
+		default:
+			return errUnknownKey
+		}
+		return err
+	})
+	if err != nil {
+		return meta, "", fmt.Errorf("front-matter %s", err)
+	}
+	return meta, rest, nil
+}
+
+// Parses a category manifest into the category.
+func parseManifest(content string, category *Category) error {
+	return parseFields(content, 1, func(key, value string) error {
+		var err error
+		switch key {
+		case "title":
+			category.Title = value
+		case "icon":
+			category.Icon = value
+		case "hidden":
+			category.Hidden, err = strconv.ParseBool(value)
+		case "order":
+			category.Order, err = strconv.Atoi(value)
+		case "lint":
+			var lint bool
+			lint, err = strconv.ParseBool(value)
+			category.SkipLint = !lint
+		default:
+			return errUnknownKey
+		}
+		return err
+	})
+}
+
+var errUnknownKey = errors.New("unknown key")
+
+// Parses "key: value" lines, calling set for each pair. Keys are lower cased
+// and both keys and values are trimmed. Blank lines and lines starting with #
+// are ignored. Line numbers in errors start at firstLine.
+func parseFields(text string, firstLine int, set func(key, value string) error) error {
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: expected key: value", firstLine+i)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if err := set(key, value); err != nil {
+			if err == errUnknownKey {
+				return fmt.Errorf("line %d: unknown key %q", firstLine+i, key)
+			}
+			return fmt.Errorf("line %d: invalid %s %q", firstLine+i, key, value)
+		}
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,139 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrSavedFull is returned by Put when the backend has no room left.
+var ErrSavedFull = errors.New("saved examples storage is full")
+
+// Backend persists saved examples keyed by their ContentID.
+type Backend interface {
+	Get(id string) (string, error)
+
+	// Put stores the content for the id. Existing content is never replaced,
+	// a different content for the same id results in an error satisfying
+	// errors.Is(err, fs.ErrExist).
+	Put(id, content string) error
+}
+
+// DiskBackend stores saved examples as files in a local directory.
+type DiskBackend struct {
+	Dir      string
+	MaxBytes int64 // total size of the saved examples, 0 is unbounded
+
+	mu   sync.Mutex
+	used int64 // size of the saved examples, once init
+	init bool
+}
+
+func (d *DiskBackend) file(id string) string {
+	return filepath.Join(d.Dir, id+".html")
+}
+
+// Get returns the content stored for the id. A missing example results in an
+// error satisfying errors.Is(err, fs.ErrNotExist).
+func (d *DiskBackend) Get(id string) (string, error) {
+	content, err := os.ReadFile(d.file(id))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Put stores the content for the id. The file is written to a temporary
+// location and linked into place, so readers never see partial content and
+// an existing file is never overwritten.
+func (d *DiskBackend) Put(id, content string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, err := os.ReadFile(d.file(id)); err == nil {
+		if string(existing) == content {
+			return nil
+		}
+		return fmt.Errorf("saved example %s: %w", id, fs.ErrExist)
+	}
+	if d.MaxBytes > 0 {
+		used, err := d.usedLocked()
+		if err != nil {
+			return err
+		}
+		if used+int64(len(content)) > d.MaxBytes {
+			return ErrSavedFull
+		}
+	}
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.Dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Link(tmp.Name(), d.file(id)); err != nil {
+		return err
+	}
+	d.used += int64(len(content))
+	return nil
+}
+
+// Returns the size of the saved examples, measuring the directory the first
+// time and then keeping count of the examples added.
+func (d *DiskBackend) usedLocked() (int64, error) {
+	if d.init {
+		return d.used, nil
+	}
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	d.used = 0
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".html") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, err
+		}
+		d.used += info.Size()
+	}
+	d.init = true
+	return d.used, nil
+}
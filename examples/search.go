@@ -0,0 +1,261 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	snippetBefore = 60
+	snippetAfter  = 120
+)
+
+var (
+	sdkCallRegexp = regexp.MustCompile(`\bFB(?:\.[A-Za-z_]+)+`)
+	markupRegexp  = regexp.MustCompile(`<[^>]*>`)
+	spaceRegexp   = regexp.MustCompile(`\s+`)
+)
+
+// Index is a full-text search index over the examples in a DB. It covers the
+// names, titles, descriptions, tags and content of examples, as well as the
+// SDK calls (FB.login, FB.ui, FB.api…) they make.
+type Index struct {
+	docs []*searchDoc
+}
+
+type searchDoc struct {
+	category *Category
+	example  *Example
+	calls    []string // SDK calls as written in the example
+	text     string   // content with markup removed
+
+	// ASCII lowercased versions of the above, for matching. Only ASCII is
+	// lowercased so byte offsets stay the same as in text.
+	name        string
+	title       string
+	description string
+	tags        string
+	lowerCalls  []string
+	lowerText   string
+}
+
+// SearchResult is an Example matching a query.
+type SearchResult struct {
+	Category *Category
+	Example  *Example
+	Score    int
+	Calls    []string   // SDK calls used by the example
+	Snippet  []Fragment // excerpt of the content around the first match
+}
+
+// Fragment is a piece of a snippet, Match is true for the matched terms.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// NewIndex builds an Index for the examples in the DB.
+func NewIndex(db *DB) *Index {
+	index := &Index{}
+	for _, category := range db.Categories {
+		for _, example := range category.Example {
+			text := markupRegexp.ReplaceAllString(example.Content, " ")
+			text = strings.TrimSpace(spaceRegexp.ReplaceAllString(text, " "))
+			doc := &searchDoc{
+				category:    category,
+				example:     example,
+				calls:       uniqueCalls(example.Content),
+				text:        text,
+				name:        asciiLower(example.Name),
+				title:       asciiLower(example.DisplayName()),
+				description: asciiLower(example.Meta.Description),
+				tags:        asciiLower(strings.Join(example.Meta.Tags, " ")),
+				lowerText:   asciiLower(text),
+			}
+			for _, call := range doc.calls {
+				doc.lowerCalls = append(doc.lowerCalls, asciiLower(call))
+			}
+			index.docs = append(index.docs, doc)
+		}
+	}
+	return index
+}
+
+// Search returns the examples matching every term in the query, best matches
+// first. Examples in hidden categories are only included if hidden is true.
+func (i *Index) Search(query string, hidden bool) []*SearchResult {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	var results []*SearchResult
+	for _, doc := range i.docs {
+		if doc.category.Hidden && !hidden {
+			continue
+		}
+		score := 0
+		for _, term := range terms {
+			termScore := doc.score(term)
+			if termScore == 0 {
+				score = 0
+				break
+			}
+			score += termScore
+		}
+		if score == 0 {
+			continue
+		}
+		results = append(results, &SearchResult{
+			Category: doc.category,
+			Example:  doc.example,
+			Score:    score,
+			Calls:    doc.calls,
+			Snippet:  doc.snippet(terms),
+		})
+	}
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+	return results
+}
+
+// Scores a single term, a zero score means the term did not match.
+func (d *searchDoc) score(term string) int {
+	score := 0
+	if strings.Contains(d.title, term) || strings.Contains(d.name, term) {
+		score += 10
+	}
+	for _, call := range d.lowerCalls {
+		if call == term {
+			score += 8
+			break
+		}
+		if strings.Contains(call, term) {
+			score += 4
+			break
+		}
+	}
+	if strings.Contains(d.tags, term) {
+		score += 5
+	}
+	if strings.Contains(d.description, term) {
+		score += 3
+	}
+	score += min(strings.Count(d.lowerText, term), 5)
+	return score
+}
+
+// Builds a snippet around the first match in the content, falling back to
+// the start of the content.
+func (d *searchDoc) snippet(terms []string) []Fragment {
+	pos := -1
+	for _, term := range terms {
+		if p := strings.Index(d.lowerText, term); p != -1 && (pos == -1 || p < pos) {
+			pos = p
+		}
+	}
+	start, end := 0, min(len(d.text), snippetBefore+snippetAfter)
+	if pos != -1 {
+		start = max(0, pos-snippetBefore)
+		end = min(len(d.text), pos+snippetAfter)
+	}
+	for start > 0 && !utf8.RuneStart(d.text[start]) {
+		start--
+	}
+	for end < len(d.text) && !utf8.RuneStart(d.text[end]) {
+		end++
+	}
+
+	var fragments []Fragment
+	if start > 0 {
+		fragments = append(fragments, Fragment{Text: "…"})
+	}
+	fragments = append(fragments, highlight(d.text[start:end], d.lowerText[start:end], terms)...)
+	if end < len(d.text) {
+		fragments = append(fragments, Fragment{Text: "…"})
+	}
+	return fragments
+}
+
+// Splits text into fragments, marking occurrences of the terms.
+func highlight(text, lower string, terms []string) []Fragment {
+	var fragments []Fragment
+	last := 0
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, term := range terms {
+			if len(term) > matched && strings.HasPrefix(lower[i:], term) {
+				matched = len(term)
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		if i > last {
+			fragments = append(fragments, Fragment{Text: text[last:i]})
+		}
+		fragments = append(fragments, Fragment{Text: text[i : i+matched], Match: true})
+		i += matched
+		last = i
+	}
+	if last < len(text) {
+		fragments = append(fragments, Fragment{Text: text[last:]})
+	}
+	return fragments
+}
+
+func queryTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.Fields(asciiLower(query)) {
+		if term = strings.Trim(term, `()"',;`); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func uniqueCalls(content string) []string {
+	var calls []string
+	seen := map[string]bool{}
+	for _, call := range sdkCallRegexp.FindAllString(content, -1) {
+		if !seen[call] {
+			seen[call] = true
+			calls = append(calls, call)
+		}
+	}
+	sort.Strings(calls)
+	return calls
+}
+
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// TemplateData is the data available to examples, which are rendered as
+// html/template templates. For example {{.AppID}} renders the application ID
+// currently in use.
+type TemplateData struct {
+	Rand     string // a random token
+	RellFBNS string // the OG namespace
+	RellURL  string // local http://www.fbrell.com/ URL
+	WwwURL   string // server specific http://www.facebook.com/ URL
+	AppID    string // the application ID
+	Version  string // the Graph API version the SDK is initialized with
+	Locale   string // the SDK locale
+	SdkURL   string // the URL the JS SDK is loaded from
+}
+
+// Used to validate templates when they are loaded.
+var sampleTemplateData = &TemplateData{
+	Rand:     "0123456789abcdef0123",
+	RellFBNS: "fbrell",
+	RellURL:  "http://www.fbrell.com/",
+	WwwURL:   "http://www.facebook.com/",
+	AppID:    "342526215814610",
+	Version:  "v25.0",
+	Locale:   "en_US",
+	SdkURL:   "https://connect.facebook.net/en_US/sdk.js",
+}
+
+// Parses the content as a template, and executes it once with sample data to
+// catch errors that html/template only reports at execution time.
+func parseTemplate(name, content string) (*template.Template, error) {
+	tpl, err := template.New(name).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := tpl.Execute(io.Discard, sampleTemplateData); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// Render writes the content of the example, executing the template with the
+// given data. Nothing is written if the template fails.
+func (e *Example) Render(w io.Writer, data *TemplateData) error {
+	if e.Template == nil {
+		_, err := io.WriteString(w, e.Content)
+		return err
+	}
+	var buf bytes.Buffer
+	if err := e.Template.Execute(&buf, data); err != nil {
+		return fmt.Errorf("Failed to render example %s: %s", e.URL, err)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
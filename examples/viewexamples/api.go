@@ -0,0 +1,161 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package viewexamples
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/daaku/ctxerr"
+	"github.com/fbsamples/fbrell/errcode"
+	"github.com/fbsamples/fbrell/examples"
+	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/view"
+)
+
+// APIPath is where the JSON API for examples is served.
+const APIPath = view.APIPath + "examples"
+
+type apiCategory struct {
+	Name     string        `json:"name"`
+	Title    string        `json:"title"`
+	Icon     string        `json:"icon,omitempty"`
+	Hidden   bool          `json:"hidden,omitempty"`
+	Examples []*apiExample `json:"examples"`
+}
+
+type apiExample struct {
+	Category    string   `json:"category"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	MinVersion  string   `json:"minVersion,omitempty"`
+	AutoRun     bool     `json:"autoRun"`
+	URL         string   `json:"url"`
+	ContentID   string   `json:"contentID"`
+	Root        string   `json:"root,omitempty"`
+	Content     string   `json:"content,omitempty"`
+}
+
+func newAPIExample(category string, e *examples.Example) *apiExample {
+	return &apiExample{
+		Category:    category,
+		Name:        e.Name,
+		Title:       e.DisplayName(),
+		Description: e.Meta.Description,
+		Tags:        e.Meta.Tags,
+		Permissions: e.Meta.Permissions,
+		MinVersion:  e.Meta.MinVersion,
+		AutoRun:     e.AutoRun,
+		URL:         e.URL,
+		ContentID:   e.ID,
+		Root:        rootName(e.Root),
+	}
+}
+
+// APIList serves the category tree along with example metadata as JSON.
+// Hidden categories are only included for employees.
+func (a *Handler) APIList(w http.ResponseWriter, r *http.Request) error {
+	employee := rellenv.IsEmployee(r.Context())
+	db := a.ExampleStore.Snapshot()
+	categories := []*apiCategory{}
+	for _, category := range db.Categories {
+		if category.Hidden && !employee {
+			continue
+		}
+		c := &apiCategory{
+			Name:     category.Name,
+			Title:    category.DisplayName(),
+			Icon:     category.Icon,
+			Hidden:   category.Hidden,
+			Examples: []*apiExample{},
+		}
+		for _, example := range category.Example {
+			c.Examples = append(c.Examples, newAPIExample(category.Name, example))
+		}
+		categories = append(categories, c)
+	}
+
+	return writeJSON(w, r, map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+// APIExample serves a single example including its raw content as JSON.
+// Examples in hidden categories are only available to employees.
+func (a *Handler) APIExample(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	examplePath := strings.TrimPrefix(r.URL.Path, APIPath)
+	if examplePath == "/" {
+		return a.APIList(w, r)
+	}
+	db := a.ExampleStore.Snapshot()
+	example, err := a.ExampleStore.LoadFrom(db, examplePath)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+
+	categoryName := strings.Split(examplePath, "/")[1]
+	category := db.FindCategory(categoryName)
+	if category != nil && category.Hidden && !rellenv.IsEmployee(ctx) {
+		return ctxerr.Wrap(ctx, errcode.New(http.StatusNotFound,
+			"Could not find category: %s", categoryName))
+	}
+
+	res := newAPIExample(categoryName, example)
+	res.Content = example.Content
+	return writeJSON(w, r, res)
+}
+
+// writeJSON writes v as JSON, or a 304 if the client already has it. The ETag
+// is computed from the encoded response, so it changes along with any of the
+// metadata and not just the example content.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	etag := `"` + examples.ContentID(string(body)) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	return err
+}
+
+// etagMatch implements the weak comparison used for If-None-Match.
+func etagMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
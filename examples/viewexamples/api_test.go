@@ -0,0 +1,213 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package viewexamples_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.trustforward"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/examples"
+	"github.com/fbsamples/fbrell/examples/viewexamples"
+	"github.com/fbsamples/fbrell/rellenv"
+)
+
+const testSecret = "secret"
+
+type funcEmpChecker func(uint64) bool
+
+func (f funcEmpChecker) Check(ctx context.Context, uid uint64) bool {
+	return f(uid)
+}
+
+type funcAppNSFetcher func(uint64) string
+
+func (f funcAppNSFetcher) Get(ctx context.Context, id uint64) string {
+	return f(id)
+}
+
+func testHandler(t *testing.T) *viewexamples.Handler {
+	dir := t.TempDir()
+	for _, f := range []struct{ category, name, content string }{
+		{"public", "one", "---\ntitle: One\n---\none"},
+		{"secret", "two", "two"},
+	} {
+		ensure.Nil(t, os.MkdirAll(filepath.Join(dir, f.category), 0755))
+		ensure.Nil(t, os.WriteFile(
+			filepath.Join(dir, f.category, f.name+".html"), []byte(f.content), 0644))
+	}
+	ensure.Nil(t, os.WriteFile(
+		filepath.Join(dir, "secret", "_category"), []byte("hidden: true\n"), 0644))
+	return &viewexamples.Handler{
+		ExampleStore: examples.NewStore(examples.MustMakeDB(dir), nil),
+	}
+}
+
+// signedRequest creates a signed_request for the given user.
+func signedRequest(userID string) string {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"algorithm": "HMAC-SHA256",
+		"issued_at": time.Now().Unix(),
+		"user_id":   userID,
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) + "." + encoded
+}
+
+func serve(t *testing.T, handler func(http.ResponseWriter, *http.Request) error,
+	target string, employee bool, header http.Header) *httptest.ResponseRecorder {
+	values := url.Values{}
+	if employee {
+		values.Set("signed_request", signedRequest("1"))
+	}
+	r := httptest.NewRequest("GET", target+"?"+values.Encode(), nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	parser := &rellenv.Parser{
+		EmpChecker:          funcEmpChecker(func(uint64) bool { return true }),
+		AppNSFetcher:        funcAppNSFetcher(func(uint64) string { return "" }),
+		App:                 fbapp.New(42, testSecret, ""),
+		SignedRequestMaxAge: time.Hour,
+		Forwarded:           &trustforward.Forwarded{},
+	}
+	env, err := parser.FromRequest(r)
+	ensure.Nil(t, err)
+	r = r.WithContext(rellenv.WithEnv(r.Context(), env))
+	w := httptest.NewRecorder()
+	if err := handler(w, r); err != nil {
+		w.Code = errCode(err)
+	}
+	return w
+}
+
+func errCode(err error) int {
+	for err != nil {
+		if code, ok := err.(interface{ Code() int }); ok {
+			return code.Code()
+		}
+		wrapper, ok := err.(interface{ Underlying() error })
+		if !ok {
+			break
+		}
+		err = wrapper.Underlying()
+	}
+	return http.StatusInternalServerError
+}
+
+func TestAPIListHidesHidden(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	w := serve(t, handler.APIList, viewexamples.APIPath, false, nil)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.StringContains(t, w.Body.String(), `"title":"One"`)
+	ensure.StringDoesNotContain(t, w.Body.String(), "secret")
+
+	w = serve(t, handler.APIList, viewexamples.APIPath, true, nil)
+	ensure.StringContains(t, w.Body.String(), `"name":"secret"`)
+}
+
+func TestAPIExample(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	w := serve(t, handler.APIExample, viewexamples.APIPath+"/public/one", false, nil)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	var res struct {
+		Content   string `json:"content"`
+		ContentID string `json:"contentID"`
+		Root      string `json:"root"`
+	}
+	ensure.Nil(t, json.NewDecoder(w.Body).Decode(&res))
+	ensure.DeepEqual(t, res.Content, "one")
+	ensure.NotDeepEqual(t, res.Root, "")
+	ensure.StringDoesNotContain(t, res.Root, string(filepath.Separator))
+	ensure.DeepEqual(t, res.ContentID, examples.ContentID("one"))
+	etag := w.Header().Get("ETag")
+	ensure.NotDeepEqual(t, etag, "")
+	w = serve(t, handler.APIExample, viewexamples.APIPath+"/public/one", false, nil)
+	ensure.DeepEqual(t, w.Header().Get("ETag"), etag)
+
+	w = serve(t, handler.APIExample, viewexamples.APIPath+"/secret/two", false, nil)
+	ensure.DeepEqual(t, w.Code, http.StatusNotFound)
+	w = serve(t, handler.APIExample, viewexamples.APIPath+"/secret/two", true, nil)
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+}
+
+func TestAPINotModified(t *testing.T) {
+	t.Parallel()
+	handler := testHandler(t)
+	w := serve(t, handler.APIExample, viewexamples.APIPath+"/public/one", false, nil)
+	etag := w.Header().Get("ETag")
+	w = serve(t, handler.APIExample, viewexamples.APIPath+"/public/one", false,
+		http.Header{"If-None-Match": {`"other", W/` + etag}})
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	ensure.DeepEqual(t, w.Body.Len(), 0)
+
+	w = serve(t, handler.APIList, viewexamples.APIPath, false, nil)
+	listETag := w.Header().Get("ETag")
+	w = serve(t, handler.APIList, viewexamples.APIPath, false,
+		http.Header{"If-None-Match": {listETag}})
+	ensure.DeepEqual(t, w.Code, http.StatusNotModified)
+	w = serve(t, handler.APIList, viewexamples.APIPath, true,
+		http.Header{"If-None-Match": {listETag}})
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.NotDeepEqual(t, w.Header().Get("ETag"), listETag)
+}
+
+func TestAPIETagFrontMatter(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "public", "one.html")
+	ensure.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+	ensure.Nil(t, os.WriteFile(file, []byte("---\ntitle: One\n---\none"), 0644))
+	handler := &viewexamples.Handler{
+		ExampleStore: examples.NewStore(examples.MustMakeDB(dir), nil),
+	}
+	exampleETag := serve(t, handler.APIExample, viewexamples.APIPath+"/public/one",
+		false, nil).Header().Get("ETag")
+	listETag := serve(t, handler.APIList, viewexamples.APIPath,
+		false, nil).Header().Get("ETag")
+
+	// only the front-matter changes, the example content stays the same
+	ensure.Nil(t, os.WriteFile(file, []byte("---\ntitle: Uno\n---\none"), 0644))
+	handler.ExampleStore = examples.NewStore(examples.MustMakeDB(dir), nil)
+	w := serve(t, handler.APIExample, viewexamples.APIPath+"/public/one", false,
+		http.Header{"If-None-Match": {exampleETag}})
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+	ensure.StringContains(t, w.Body.String(), `"title":"Uno"`)
+	w = serve(t, handler.APIList, viewexamples.APIPath, false,
+		http.Header{"If-None-Match": {listETag}})
+	ensure.DeepEqual(t, w.Code, http.StatusOK)
+}
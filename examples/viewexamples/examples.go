@@ -32,8 +32,10 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"sort"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/daaku/ctxerr"
 	"github.com/daaku/go.fburl"
@@ -44,6 +46,7 @@ import (
 	"github.com/facebookgo/counting"
 	"github.com/fbsamples/fbrell/examples"
 	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/rellenv/viewcontext"
 	"github.com/fbsamples/fbrell/view"
 )
 
@@ -63,36 +66,6 @@ var (
 		rellenv.PageTab: "Page Tab",
 		rellenv.Canvas:  "Canvas",
 	}
-	localeOptions = []string{
-		"en_US", "af_ZA", "ak_GH", "am_ET", "ar_AR", "as_IN", "ay_BO", "az_AZ",
-		"be_BY", "bg_BG", "bm_ML", "bn_IN", "bp_IN", "br_FR", "bs_BA", "bv_DE",
-		"ca_ES", "cb_IQ", "ck_US", "co_FR", "cs_CZ", "cx_PH", "cy_GB",
-		"da_DK", "de_DE",
-		"eh_IN", "el_GR", "em_ZM", "en_GB", "en_IN", "en_OP", "en_PI", "en_UD", "en_XA", "eo_EO",
-		"es_CL", "es_CO", "es_ES", "es_LA", "es_MX", "es_VE", "et_EE", "eu_ES",
-		"fa_IR", "fb_AA", "fb_AC", "fb_AR", "fb_HA", "fb_HX", "fb_LL", "fb_LS", "fb_LT", "fb_RL", "fb_ZH", "fbt_AC",
-		"ff_NG", "fi_FI", "fn_IT", "fo_FO", "fr_CA", "fr_FR", "fv_NG", "fy_NL",
-		"ga_IE", "gl_ES", "gn_PY", "gu_IN", "gx_GR",
-		"ha_NG", "he_IL", "hi_FB", "hi_IN", "hr_HR", "ht_HT", "hu_HU", "hy_AM",
-		"id_ID", "ig_NG", "ik_US", "is_IS", "it_IT", "iu_CA",
-		"ja_JP", "ja_KS", "jv_ID",
-		"ka_GE", "kk_KZ", "km_KH", "kn_IN", "ko_KR", "ks_IN", "ku_TR", "ky_KG",
-		"la_VA", "lg_UG", "li_NL", "ln_CD", "lo_LA", "lr_IT", "lt_LT", "lv_LV",
-		"mg_MG", "mi_NZ", "mk_MK", "ml_IN", "mn_MN", "mos_BF", "mr_IN", "ms_MY", "mt_MT", "my_MM",
-		"nb_NO", "nd_ZW", "ne_NP", "nh_MX", "nl_BE", "nl_NL", "nn_NO", "nr_ZA", "ns_ZA", "ny_MW",
-		"om_ET", "or_IN",
-		"pa_IN", "pcm_NG", "pl_PL", "ps_AF", "pt_BR", "pt_PT",
-		"qb_DE", "qc_GT", "qe_US", "qk_DZ", "qr_GR", "qs_DE", "qt_US", "qu_PE", "qv_IT", "qz_MM",
-		"rm_CH", "rn_BI", "ro_RO", "ru_RU", "rw_RW",
-		"sa_IN", "sc_IT", "se_NO", "si_LK", "sk_SK", "sl_SI", "sn_ZW", "so_SO", "sq_AL", "sr_RS", "ss_SZ", "st_ZA", "su_ID", "sv_SE", "sw_KE", "sy_SY", "sz_PL",
-		"ta_IN", "te_IN", "tg_TJ", "th_TH", "ti_ET", "tk_TM", "tl_PH", "tl_ST", "tn_BW", "tq_AR", "tpi_PG", "tr_TR", "ts_ZA", "tt_RU", "tz_MA",
-		"uk_UA", "ur_PK", "uz_UZ",
-		"ve_ZA", "vi_VN",
-		"wo_SN",
-		"xh_ZA",
-		"yi_DE", "yo_NG",
-		"zh_CN", "zh_HK", "zh_TW", "zu_ZA", "zz_TR",
-	}
 )
 
 type Handler struct {
@@ -100,18 +73,20 @@ type Handler struct {
 	Static       *static.Handler
 }
 
-// Parse the Env and an Example.
-func (h *Handler) parse(r *http.Request) (*rellenv.Env, *examples.Example, error) {
+// Parse the Env and an Example, along with the DB snapshot the Example was
+// loaded from.
+func (h *Handler) parse(r *http.Request) (*rellenv.Env, *examples.Example, *examples.DB, error) {
 	ctx := r.Context()
 	context, err := rellenv.FromContext(ctx)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	example, err := h.ExampleStore.Load(r.URL.Path)
+	db := h.ExampleStore.Snapshot()
+	example, err := h.ExampleStore.LoadFrom(db, r.URL.Path)
 	if err != nil {
-		return nil, nil, ctxerr.Wrap(ctx, err)
+		return nil, nil, nil, ctxerr.Wrap(ctx, err)
 	}
-	return context, example, nil
+	return context, example, db, nil
 }
 
 func (a *Handler) List(w http.ResponseWriter, r *http.Request) error {
@@ -124,14 +99,14 @@ func (a *Handler) List(w http.ResponseWriter, r *http.Request) error {
 		Context: ctx,
 		Env:     env,
 		Static:  a.Static,
-		DB:      a.ExampleStore.DB,
+		DB:      a.ExampleStore.Snapshot(),
 	})
 	return err
 }
 
 func (a *Handler) Example(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
-	env, example, err := a.parse(r)
+	env, example, db, err := a.parse(r)
 	if err != nil {
 		return err
 	}
@@ -142,22 +117,34 @@ func (a *Handler) Example(w http.ResponseWriter, r *http.Request) error {
 		Env:     env,
 		Static:  a.Static,
 		Example: example,
-		DB:      a.ExampleStore.DB,
+		DB:      db,
 	})
 	return err
 }
 
-// sortedCategories returns the DB categories sorted by name, excluding hidden ones.
-func sortedCategories(db *examples.DB) []*examples.Category {
+// Save stores the posted code and redirects to its shareable URL.
+func (a *Handler) Save(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	env, err := rellenv.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	example, err := a.ExampleStore.Save(r.PostFormValue("code"))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+	http.Redirect(w, r, env.URL(example.URL).String(), http.StatusSeeOther)
+	return nil
+}
+
+// visibleCategories returns the DB categories in order, excluding hidden ones.
+func visibleCategories(db *examples.DB) []*examples.Category {
 	var cats []*examples.Category
-	for _, cat := range db.Category {
+	for _, cat := range db.Categories {
 		if !cat.Hidden {
 			cats = append(cats, cat)
 		}
 	}
-	sort.Slice(cats, func(i, j int) bool {
-		return cats[i].Name < cats[j].Name
-	})
 	return cats
 }
 
@@ -297,7 +284,7 @@ type sidebarNav struct {
 }
 
 func (s *sidebarNav) HTML(ctx context.Context) (h.HTML, error) {
-	cats := sortedCategories(s.DB)
+	cats := visibleCategories(s.DB)
 	var categories h.Frag
 	for _, category := range cats {
 		var items h.Frag
@@ -305,7 +292,9 @@ func (s *sidebarNav) HTML(ctx context.Context) (h.HTML, error) {
 			items = append(items, &h.A{
 				Class: "sidebar-item",
 				HREF:  s.Env.URL(example.URL).String(),
-				Inner: h.String(example.Name),
+				Title: example.Meta.Description,
+				Data:  exampleData(example),
+				Inner: h.String(example.DisplayName()),
 			})
 		}
 		categories = append(categories, &h.Div{
@@ -315,7 +304,7 @@ func (s *sidebarNav) HTML(ctx context.Context) (h.HTML, error) {
 					Class: "sidebar-category-header",
 					Inner: h.Frag{
 						&h.Span{Class: "sidebar-toggle", Inner: h.Unsafe("&#9660;")},
-						&h.Span{Class: "sidebar-category-name", Inner: h.String(category.Name)},
+						&h.Span{Class: "sidebar-category-name", Inner: h.String(category.DisplayName())},
 					},
 				},
 				&h.Div{
@@ -430,7 +419,7 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 
 	// Build locale dropdown options
 	localeOpts := h.Frag{}
-	for _, loc := range localeOptions {
+	for _, loc := range rellenv.Locales {
 		localeOpts = append(localeOpts, &h.Option{
 			Value:    loc,
 			Selected: s.Env.Locale() == loc,
@@ -438,10 +427,61 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 		})
 	}
 
+	// Build version dropdown options, newest first
+	versionOpts := h.Frag{}
+	now := time.Now()
+	for i := len(rellenv.Versions) - 1; i >= 0; i-- {
+		version := rellenv.Versions[i]
+		label := version.Name
+		switch {
+		case version == rellenv.DefaultVersion:
+			label += " (default)"
+		case version.IsDeprecated(now):
+			label += " (deprecated)"
+		}
+		versionOpts = append(versionOpts, &h.Option{
+			Value:    version.Name,
+			Selected: s.Env.Version == version.Name,
+			Inner:    h.String(label),
+		})
+	}
+
+	// Offer a picker when several apps are configured, otherwise the app ID
+	var appSetting h.HTML = &settingsField{
+		Label:       "App ID",
+		Name:        "appid",
+		Value:       appID,
+		Placeholder: "342526215814610",
+	}
+	if apps := s.Env.Apps(); len(apps) > 1 {
+		appOpts := h.Frag{}
+		if _, ok := apps.Find(rellenv.FbApp(s.Context).ID()); !ok {
+			appOpts = append(appOpts, &h.Option{
+				Value:    appID,
+				Selected: true,
+				Inner:    h.String(appID),
+			})
+		}
+		for _, app := range apps {
+			id := strconv.FormatUint(app.ID(), 10)
+			appOpts = append(appOpts, &h.Option{
+				Value:    id,
+				Selected: id == appID,
+				Inner:    h.String(app.Name() + " (" + id + ")"),
+			})
+		}
+		appSetting = &settingsSelect{
+			Label:   "App",
+			Name:    "appid",
+			Options: appOpts,
+			Default: strconv.FormatUint(apps[0].ID(), 10),
+		}
+	}
+
 	fedcmContextOpts := h.Frag{
-		&h.Option{Value: "", Selected: s.Env.FedCMContext == "", Inner: h.String("(none)")},
+		emptyOption("(none)", s.Env.FedCMContext == ""),
 	}
-	for _, fctx := range []string{"signin", "signup", "use"} {
+	for _, fctx := range rellenv.FedCMContexts {
 		fedcmContextOpts = append(fedcmContextOpts, &h.Option{
 			Value:    fctx,
 			Selected: s.Env.FedCMContext == fctx,
@@ -449,6 +489,17 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 		})
 	}
 
+	fedcmModeOpts := h.Frag{
+		emptyOption("(browser default)", s.Env.FedCMMode == ""),
+	}
+	for _, mode := range rellenv.FedCMModes {
+		fedcmModeOpts = append(fedcmModeOpts, &h.Option{
+			Value:    mode,
+			Selected: s.Env.FedCMMode == mode,
+			Inner:    h.String(mode),
+		})
+	}
+
 	return &h.Div{
 		ID:    "settings-drawer",
 		Class: "settings-drawer",
@@ -467,23 +518,18 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 			&h.Div{
 				Class: "drawer-body",
 				Inner: h.Frag{
-					&settingsField{
-						Label:       "App ID",
-						Name:        "appid",
-						Value:       appID,
-						Placeholder: "342526215814610",
-					},
+					appSetting,
 					&settingsField{
 						Label:       "Server",
 						Name:        "server",
 						Value:       s.Env.Env,
 						Placeholder: "e.g. beta, latest, intern",
 					},
-					&settingsField{
-						Label:       "Version",
-						Name:        "version",
-						Value:       s.Env.Version,
-						Placeholder: "v25.0",
+					&settingsSelect{
+						Label:   "Version",
+						Name:    "version",
+						Options: versionOpts,
+						Default: rellenv.DefaultVersion.Name,
 					},
 					&settingsSelect{
 						Label:   "Locale",
@@ -537,11 +583,35 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 						Name:    "fedcmContext",
 						Options: fedcmContextOpts,
 					},
+					&settingsSelect{
+						Label:   "FedCM Mode",
+						Name:    "fedcmMode",
+						Options: fedcmModeOpts,
+					},
+					&settingsField{
+						Label:       "FedCM Login Hint",
+						Name:        "fedcmLoginHint",
+						Value:       s.Env.FedCMLoginHint,
+						Placeholder: "e.g. user@example.com",
+					},
+					&settingsField{
+						Label:       "FedCM Domain Hint",
+						Name:        "fedcmDomainHint",
+						Value:       s.Env.FedCMDomainHint,
+						Placeholder: "e.g. example.com or any",
+					},
+					&settingsField{
+						Label:       "FedCM Nonce",
+						Name:        "fedcmNonce",
+						Value:       s.Env.FedCMNonce,
+						Placeholder: "e.g. a random string",
+					},
 				},
 			},
 			&h.Div{
 				Class: "drawer-footer",
 				Inner: h.Frag{
+					&rememberSettings{Env: s.Env, Example: s.Example},
 					&h.Div{
 						ID:    "rell-url-preview",
 						Class: "rell-url-preview",
@@ -560,6 +630,78 @@ func (s *settingsDrawer) HTML(ctx context.Context) (h.HTML, error) {
 	}, nil
 }
 
+// rememberSettings renders the control to store the settings in the settings
+// cookie, along with a link to forget them once they are.
+type rememberSettings struct {
+	Env     *rellenv.Env
+	Example *examples.Example
+}
+
+func (r *rememberSettings) HTML(ctx context.Context) (h.HTML, error) {
+	var reset h.HTML
+	if r.Env.Remembered() {
+		reset = &h.Form{
+			Class:  "settings-reset-form",
+			Action: viewcontext.SettingsPath + "reset?next=" + url.QueryEscape(r.Example.URL),
+			Method: "post",
+			Inner: &h.Button{
+				ID:    "rell-settings-reset",
+				Class: "settings-reset",
+				Type:  "submit",
+				Title: "Forget the remembered settings",
+				Inner: h.String("Reset"),
+			},
+		}
+	}
+	return &h.Div{
+		Class: "setting-group setting-group-checkbox",
+		Inner: h.Frag{
+			&h.Label{
+				Class: "setting-label",
+				Inner: h.Frag{
+					&h.Input{
+						ID:      "rell-settings-remember",
+						Type:    "checkbox",
+						Checked: r.Env.Remembered(),
+					},
+					h.String(" Remember these settings"),
+				},
+			},
+			reset,
+		},
+	}, nil
+}
+
+// envWarnings renders a dismissible banner listing the Env warnings, if any.
+type envWarnings struct {
+	Env *rellenv.Env
+}
+
+func (w *envWarnings) HTML(ctx context.Context) (h.HTML, error) {
+	warnings := w.Env.Warnings()
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	items := h.Frag{
+		&h.Button{
+			ID:    "rell-warnings-dismiss",
+			Class: "btn-icon env-warnings-dismiss",
+			Inner: h.Unsafe("&#10005;"),
+		},
+	}
+	for _, warning := range warnings {
+		items = append(items, &h.Div{
+			Class: "env-warning",
+			Inner: h.String(warning.Message),
+		})
+	}
+	return &h.Div{
+		ID:    "rell-warnings",
+		Class: "env-warnings",
+		Inner: items,
+	}, nil
+}
+
 type settingsField struct {
 	Label       string
 	Name        string
@@ -593,6 +735,7 @@ type settingsSelect struct {
 	Label   string
 	Name    string
 	Options h.HTML
+	Default string // optional, overrides the default known to rell.js
 }
 
 func (s *settingsSelect) HTML(ctx context.Context) (h.HTML, error) {
@@ -603,15 +746,29 @@ func (s *settingsSelect) HTML(ctx context.Context) (h.HTML, error) {
 				Class: "setting-label",
 				Inner: h.String(s.Label),
 			},
-			&h.Select{
-				Class: "rell-setting",
-				Name:  s.Name,
+			&h.Node{
+				Tag: "select",
+				Attributes: h.Attributes{
+					"class":        "rell-setting",
+					"name":         s.Name,
+					"data-default": s.Default,
+				},
 				Inner: s.Options,
 			},
 		},
 	}, nil
 }
 
+// emptyOption renders an option with an empty value, which h.Option omits
+// making the browser use the label as the value instead.
+func emptyOption(label string, selected bool) h.HTML {
+	attr := ""
+	if selected {
+		attr = " selected"
+	}
+	return h.Unsafe(`<option value=""` + attr + `>` + template.HTMLEscapeString(label) + `</option>`)
+}
+
 type settingsCheckbox struct {
 	Label   string
 	Name    string
@@ -667,6 +824,7 @@ func (p *page) HTML(ctx context.Context) (h.HTML, error) {
 					Env:     p.Env,
 					Example: p.Example,
 				},
+				&envWarnings{Env: p.Env},
 				&h.Div{
 					Class: "main-layout",
 					Inner: h.Frag{
@@ -691,17 +849,35 @@ func (p *page) HTML(ctx context.Context) (h.HTML, error) {
 									Inner: h.Frag{
 										&h.Div{
 											Class: "toolbar-left",
-											Inner: &h.Span{
-												Class: "example-title",
-												Inner: h.String(p.Example.Title),
+											Inner: h.Frag{
+												&h.Node{
+													Tag: "span",
+													Attributes: h.Attributes{
+														"class": "example-title",
+														"title": p.Example.Meta.Description,
+													},
+													Inner: h.String(p.Example.Title),
+												},
+												&exampleBadges{
+													Example: p.Example,
+													Root:    exampleRoot(p.DB, p.Example),
+												},
 											},
 										},
 										&h.Div{
 											Class: "toolbar-right",
-											Inner: &h.Button{
-												ID:    "rell-run-code",
-												Class: "btn btn-primary",
-												Inner: h.Unsafe("&#9654; Run"),
+											Inner: h.Frag{
+												&h.Button{
+													ID:    "rell-save-code",
+													Class: "btn",
+													Title: "Save and get a shareable link",
+													Inner: h.String("Save"),
+												},
+												&h.Button{
+													ID:    "rell-run-code",
+													Class: "btn btn-primary",
+													Inner: h.Unsafe("&#9654; Run"),
+												},
 											},
 										},
 									},
@@ -760,17 +936,29 @@ type examplesList struct {
 }
 
 func (l *examplesList) HTML(ctx context.Context) (h.HTML, error) {
-	cats := sortedCategories(l.DB)
+	cats := visibleCategories(l.DB)
 	var cards h.Frag
 	for _, category := range cats {
 		var links h.Frag
 		for _, example := range category.Example {
+			var description h.HTML
+			if example.Meta.Description != "" {
+				description = &h.Span{
+					Class: "example-description",
+					Inner: h.String(example.Meta.Description),
+				}
+			}
 			links = append(links, &h.A{
 				Class: "example-link",
 				HREF:  l.Env.URL(example.URL).String(),
-				Inner: &h.Span{
-					Class: "example-name",
-					Inner: h.String(example.Name),
+				Data:  exampleData(example),
+				Inner: h.Frag{
+					&h.Span{
+						Class: "example-name",
+						Inner: h.String(example.DisplayName()),
+					},
+					description,
+					rootLabel(exampleRoot(l.DB, example)),
 				},
 			})
 		}
@@ -780,8 +968,8 @@ func (l *examplesList) HTML(ctx context.Context) (h.HTML, error) {
 				&h.Div{
 					Class: "category-header",
 					Inner: h.Frag{
-						&h.Span{Class: "category-icon", Inner: h.String(categoryIcon(category.Name))},
-						&h.H2{Inner: h.String(category.Name)},
+						&h.Span{Class: "category-icon", Inner: h.String(categoryIcon(category))},
+						&h.H2{Inner: h.String(category.DisplayName())},
 					},
 				},
 				&h.Div{
@@ -802,6 +990,7 @@ func (l *examplesList) HTML(ctx context.Context) (h.HTML, error) {
 					Context: l.Context,
 					Env:     l.Env,
 				},
+				&envWarnings{Env: l.Env},
 				&h.Div{
 					Class: "examples-page",
 					Inner: h.Frag{
@@ -809,12 +998,7 @@ func (l *examplesList) HTML(ctx context.Context) (h.HTML, error) {
 							Class: "examples-header",
 							Inner: h.Frag{
 								&h.H1{Inner: h.String("Examples")},
-								&h.Input{
-									ID:          "examples-search",
-									Class:       "examples-search",
-									Type:        "text",
-									Placeholder: "Search examples...",
-								},
+								&searchForm{Env: l.Env},
 							},
 						},
 						&h.Div{
@@ -829,19 +1013,100 @@ func (l *examplesList) HTML(ctx context.Context) (h.HTML, error) {
 	}, nil
 }
 
-func categoryIcon(name string) string {
-	switch name {
-	case "Facebook Login":
-		return "\xf0\x9f\x94\x91" // key
-	case "Graph API":
-		return "\xf0\x9f\x93\x8a" // chart
-	case "Sharing":
-		return "\xf0\x9f\x94\x97" // link
-	case "auth":
-		return "\xf0\x9f\x94\x90" // lock
-	default:
-		return "\xf0\x9f\x93\x9d" // memo
+func categoryIcon(category *examples.Category) string {
+	if category.Icon != "" {
+		return category.Icon
+	}
+	return "\xf0\x9f\x93\x9d" // memo
+}
+
+// exampleData returns the data attributes used for client side filtering.
+func exampleData(e *examples.Example) map[string]interface{} {
+	if len(e.Meta.Tags) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"tags": strings.Join(e.Meta.Tags, " "),
+	}
+}
+
+// exampleRoot returns the directory an example was loaded from, or an empty
+// string if there is only one.
+func exampleRoot(db *examples.DB, e *examples.Example) string {
+	if len(db.Roots) < 2 {
+		return ""
 	}
+	return e.Root
+}
+
+// rootName returns the base name of an examples directory, which unlike the
+// full path is safe to show to anyone.
+func rootName(root string) string {
+	if root == "" {
+		return ""
+	}
+	return filepath.Base(root)
+}
+
+// rootLabel renders a badge naming an examples directory.
+func rootLabel(root string) h.HTML {
+	if root == "" {
+		return nil
+	}
+	name := rootName(root)
+	return &h.Node{
+		Tag: "span",
+		Attributes: h.Attributes{
+			"class": "example-badge example-badge-root",
+			"title": "Loaded from the " + name + " examples",
+		},
+		Inner: h.String(name),
+	}
+}
+
+// exampleBadges renders the front-matter tags, permissions and minimum
+// version of an example, along with the Root it was loaded from if set.
+type exampleBadges struct {
+	Example *examples.Example
+	Root    string
+}
+
+func (b *exampleBadges) HTML(ctx context.Context) (h.HTML, error) {
+	meta := b.Example.Meta
+	var badges h.Frag
+	if meta.MinVersion != "" {
+		badges = append(badges, &h.Node{
+			Tag: "span",
+			Attributes: h.Attributes{
+				"class": "example-badge example-badge-version",
+				"title": "Minimum Graph API version",
+			},
+			Inner: h.String(meta.MinVersion + "+"),
+		})
+	}
+	for _, perm := range meta.Permissions {
+		badges = append(badges, &h.Node{
+			Tag: "span",
+			Attributes: h.Attributes{
+				"class": "example-badge example-badge-permission",
+				"title": "Requires the " + perm + " permission",
+			},
+			Inner: h.String(perm),
+		})
+	}
+	for _, tag := range meta.Tags {
+		badges = append(badges, &h.Span{
+			Class: "example-badge",
+			Inner: h.String(tag),
+		})
+	}
+	if b.Root != "" {
+		badges = append(badges, rootLabel(b.Root))
+	}
+	if len(badges) == 0 {
+		return nil, nil
+	}
+	return &h.Span{Class: "example-badges", Inner: badges}, nil
 }
 
 type exampleContent struct {
@@ -857,33 +1122,21 @@ func (c *exampleContent) HTML(ctx context.Context) (h.HTML, error) {
 // Renders the example content including support for context sensitive
 // text substitution.
 func (c *exampleContent) Write(ctx context.Context, w io.Writer) (int, error) {
-	e := c.Example
 	wwwURL := fburl.URL{
 		Env: rellenv.FbEnv(c.Context),
 	}
-	w = htmlwriter.New(w)
-	tpl, err := template.New("example-" + e.URL).Parse(string(e.Content))
-	if err != nil {
-		// if template parsing fails, we ignore it. it's probably malformed html
-		return fmt.Fprint(w, e.Content)
-	}
-	countingW := counting.NewWriter(w)
-	err = tpl.Execute(countingW,
-		struct {
-			Rand     string // a random token
-			RellFBNS string // the OG namespace
-			RellURL  string // local http://www.fbrell.com/ URL
-			WwwURL   string // server specific http://www.facebook.com/ URL
-		}{
-			Rand:     randString(10),
-			RellFBNS: rellenv.FbApp(c.Context).Namespace(),
-			RellURL:  c.Env.AbsoluteURL("/").String(),
-			WwwURL:   wwwURL.String(),
-		})
-	if err != nil {
-		// if template execution fails, we ignore it. it's probably malformed html
-		return fmt.Fprint(w, e.Content)
-	}
+	fbApp := rellenv.FbApp(c.Context)
+	countingW := counting.NewWriter(htmlwriter.New(w))
+	err := c.Example.Render(countingW, &examples.TemplateData{
+		Rand:     randString(10),
+		RellFBNS: fbApp.Namespace(),
+		RellURL:  c.Env.AbsoluteURL("/").String(),
+		WwwURL:   wwwURL.String(),
+		AppID:    strconv.FormatUint(fbApp.ID(), 10),
+		Version:  c.Env.Version,
+		Locale:   c.Env.Locale(),
+		SdkURL:   c.Env.SdkURL(),
+	})
 	return countingW.Count(), err
 }
 
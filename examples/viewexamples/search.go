@@ -0,0 +1,157 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package viewexamples
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.static"
+	"github.com/fbsamples/fbrell/examples"
+	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/view"
+)
+
+// SearchPath is where example search results are served.
+const SearchPath = "/examples/search"
+
+// Search renders the examples matching the q parameter. Hidden categories are
+// only searched for employees.
+func (a *Handler) Search(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	env, err := rellenv.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	query := r.FormValue("q")
+	db := a.ExampleStore.Snapshot()
+	_, err = h.Write(ctx, w, &searchPage{
+		Context: ctx,
+		Env:     env,
+		Static:  a.Static,
+		Query:   query,
+		Results: db.Index.Search(query, rellenv.IsEmployee(ctx)),
+	})
+	return err
+}
+
+// searchForm renders the search box, keeping the current settings.
+type searchForm struct {
+	Env   *rellenv.Env
+	Query string
+}
+
+func (f *searchForm) HTML(ctx context.Context) (h.HTML, error) {
+	return &h.Form{
+		Class:  "examples-search-form",
+		Action: SearchPath,
+		Method: "GET",
+		Inner: h.Frag{
+			h.HiddenInputs(f.Env.Values()),
+			&h.Input{
+				ID:          "examples-search",
+				Class:       "examples-search",
+				Type:        "search",
+				Name:        "q",
+				Value:       f.Query,
+				Placeholder: "Search examples...",
+			},
+		},
+	}, nil
+}
+
+type searchPage struct {
+	Context context.Context
+	Env     *rellenv.Env
+	Static  *static.Handler
+	Query   string
+	Results []*examples.SearchResult
+}
+
+func (p *searchPage) HTML(ctx context.Context) (h.HTML, error) {
+	var items h.Frag
+	for _, result := range p.Results {
+		var calls h.Frag
+		for _, call := range result.Calls {
+			calls = append(calls, &h.Span{Class: "example-badge", Inner: h.String(call)})
+		}
+		var snippet h.Frag
+		for _, fragment := range result.Snippet {
+			if fragment.Match {
+				snippet = append(snippet, &h.Node{Tag: "mark", Inner: h.String(fragment.Text)})
+			} else {
+				snippet = append(snippet, h.String(fragment.Text))
+			}
+		}
+		items = append(items, &h.A{
+			Class: "search-result",
+			HREF:  p.Env.URL(result.Example.URL).String(),
+			Inner: h.Frag{
+				&h.Div{
+					Class: "search-result-title",
+					Inner: h.Frag{
+						&h.Span{Class: "category-icon", Inner: h.String(categoryIcon(result.Category))},
+						h.String(result.Category.DisplayName() + " · " + result.Example.DisplayName()),
+					},
+				},
+				&h.Div{Class: "search-result-snippet", Inner: snippet},
+				&h.Div{Class: "example-badges", Inner: calls},
+			},
+		})
+	}
+
+	summary := "Enter a name, title, tag or SDK call like FB.login to search."
+	if p.Query != "" {
+		summary = strconv.Itoa(len(p.Results)) + " results for “" + p.Query + "”"
+	}
+
+	return &view.Page{
+		Title: "Search Examples",
+		Class: "examples",
+		Body: &h.Div{
+			Class: "app",
+			Inner: h.Frag{
+				&headerBar{
+					Context: p.Context,
+					Env:     p.Env,
+				},
+				&envWarnings{Env: p.Env},
+				&h.Div{
+					Class: "examples-page",
+					Inner: h.Frag{
+						&h.Div{
+							Class: "examples-header",
+							Inner: h.Frag{
+								&h.H1{Inner: h.String("Search")},
+								&searchForm{Env: p.Env, Query: p.Query},
+							},
+						},
+						&h.P{Class: "search-summary", Inner: h.String(summary)},
+						&h.Div{Class: "search-results", Inner: items},
+					},
+				},
+				&statusBar{},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,143 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package examples
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultWatchDelay = 250 * time.Millisecond
+
+// Watcher rebuilds the DB for a Store when files under the on-disk Sources
+// are added, edited or removed. Rebuilds happen off to the side and the new DB
+// is swapped in atomically, so readers holding a Snapshot are unaffected. A
+// rebuild that fails is logged and the previous DB stays in place.
+type Watcher struct {
+	Store   *Store
+	Sources []Source // as passed to MakeDBFrom
+	Logger  Logger
+	Delay   time.Duration // quiet period before rebuilding, defaults to 250ms
+
+	fsw  *fsnotify.Watcher
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Start begins watching the Sources in a background goroutine.
+func (w *Watcher) Start() error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.fsw = fsw
+	w.done = make(chan struct{})
+	if err := w.addDirs(); err != nil {
+		fsw.Close()
+		return err
+	}
+	w.wg.Add(1)
+	go w.run()
+	return nil
+}
+
+// Close stops watching and waits for the background goroutine to exit.
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.fsw.Close()
+	w.wg.Wait()
+	return err
+}
+
+// fsnotify is not recursive, so every directory is watched individually.
+// Adding an already watched directory is a no-op.
+func (w *Watcher) addDirs() error {
+	for _, source := range w.Sources {
+		if source.Dir == "" {
+			continue
+		}
+		err := filepath.WalkDir(source.Dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return w.fsw.Add(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+	delay := w.Delay
+	if delay == 0 {
+		delay = defaultWatchDelay
+	}
+
+	// editors tend to generate bursts of events for a single save, so we wait
+	// for things to settle before rebuilding.
+	var rebuild <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			rebuild = time.After(delay)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.Logger.Printf("Examples watcher error: %s", err)
+		case <-rebuild:
+			rebuild = nil
+			w.rebuild()
+		}
+	}
+}
+
+func (w *Watcher) rebuild() {
+	if err := w.addDirs(); err != nil {
+		w.Logger.Printf("Failed to watch new example directories: %s", err)
+	}
+	db, err := MakeDBFrom(w.Sources...)
+	if err != nil {
+		w.Logger.Printf("Keeping previous examples, reload failed: %s", err)
+		return
+	}
+	w.Store.Swap(db)
+	w.Logger.Printf("Reloaded examples from %s", strings.Join(db.Roots, ", "))
+}
@@ -0,0 +1,128 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package fakegraph implements a fake Graph API backed by a JSON fixture, for
+// exercising the app namespace and employee lookups without a network. The
+// fixture maps object ids to their fields:
+//
+//	{
+//	  "342526215814610": {"namespace": "fbrell"},
+//	  "4": {"is_employee": true, "email": "zuck@example.com"}
+//	}
+//
+// A Server answers GET /{id}, optionally with a version prefix and the fields
+// parameter, with the object's fields and id. Unknown ids get the Graph API
+// error with code 100. It can be served over HTTP, or used directly as the
+// Transport of a fbapi.Client.
+package fakegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var versionRegexp = regexp.MustCompile(`^v\d+\.\d+$`)
+
+// Fixture maps object ids to their fields.
+type Fixture map[string]map[string]interface{}
+
+// LoadFixture reads a JSON fixture file.
+func LoadFixture(path string) (Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		return nil, fmt.Errorf("Invalid fake Graph API fixture %s: %s", path, err)
+	}
+	return fixture, nil
+}
+
+// Server is the fake Graph API.
+type Server struct {
+	Fixture Fixture
+}
+
+// ServeHTTP answers object lookups from the fixture.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusBadRequest, "Only GET is supported by the fake Graph API.")
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 2 && versionRegexp.MatchString(parts[0]) {
+		parts = parts[1:]
+	}
+	if len(parts) != 1 || parts[0] == "" {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("Unsupported path %q in the fake Graph API.", r.URL.Path))
+		return
+	}
+	id := parts[0]
+	object, ok := s.Fixture[id]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(
+			"Unsupported get request. Object with ID '%s' does not exist in the fixture.", id))
+		return
+	}
+
+	result := map[string]interface{}{}
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			if v, ok := object[field]; ok {
+				result[field] = v
+			}
+		}
+	} else {
+		for field, v := range object {
+			result[field] = v
+		}
+	}
+	result["id"] = id
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// RoundTrip answers the request with ServeHTTP instead of the network.
+func (s *Server) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, r)
+	res := rec.Result()
+	res.Request = r
+	return res, nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "GraphMethodException",
+			"code":    100,
+		},
+	})
+}
@@ -0,0 +1,133 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package fakegraph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapi"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/cache"
+	"github.com/fbsamples/fbrell/fakegraph"
+	"github.com/fbsamples/fbrell/rellenv/appns"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+func fixtureServer(t *testing.T) *fakegraph.Server {
+	fixture, err := fakegraph.LoadFixture("testdata/fixture.json")
+	ensure.Nil(t, err)
+	return &fakegraph.Server{Fixture: fixture}
+}
+
+func get(t *testing.T, client *fbapi.Client, path string) (map[string]interface{}, error) {
+	u, err := url.Parse(path)
+	ensure.Nil(t, err)
+	var result map[string]interface{}
+	_, err = client.Do(&http.Request{Method: "GET", URL: u}, &result)
+	return result, err
+}
+
+func TestRoundTripper(t *testing.T) {
+	t.Parallel()
+	client := &fbapi.Client{Transport: fixtureServer(t)}
+	ctx := context.Background()
+
+	fetcher := &appns.Fetcher{
+		FbApiClient: client,
+		Logger:      discardLogger{},
+		Cache:       &cache.Cache[uint64, string]{},
+	}
+	ensure.DeepEqual(t, fetcher.Get(ctx, 342526215814610), "fbrell")
+	ensure.DeepEqual(t, fetcher.Get(ctx, 1), "")
+
+	checker := &empcheck.Checker{
+		FbApiClient: client,
+		App:         fbapp.New(1, "secret", ""),
+		Logger:      discardLogger{},
+		Cache:       &cache.Cache[uint64, bool]{},
+	}
+	ensure.True(t, checker.Check(ctx, 4))
+	ensure.False(t, checker.Check(ctx, 5))
+	ensure.False(t, checker.Check(ctx, 6))
+	ensure.DeepEqual(t, checker.Stats().Errors, int64(0))
+
+	checker = &empcheck.Checker{
+		FbApiClient: client,
+		App:         fbapp.New(1, "secret", ""),
+		Logger:      discardLogger{},
+		Cache:       &cache.Cache[uint64, bool]{},
+		Domains:     []string{"example.com"},
+	}
+	ensure.True(t, checker.Check(ctx, 4))
+	ensure.False(t, checker.Check(ctx, 5))
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+	client := &fbapi.Client{Transport: fixtureServer(t)}
+	result, err := get(t, client, "/v21.0/4?fields=is_employee,name")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, result, map[string]interface{}{"id": "4", "is_employee": true})
+
+	result, err = get(t, client, "/5")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, result, map[string]interface{}{
+		"id": "5", "is_employee": false, "email": "jane@example.org"})
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+	client := &fbapi.Client{Transport: fixtureServer(t)}
+	for _, path := range []string{"/6", "/4/friends", "/"} {
+		_, err := get(t, client, path)
+		ensure.DeepEqual(t, err.(*fbapi.Error).Code, 100, path)
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(fixtureServer(t))
+	defer server.Close()
+	base, err := url.Parse(server.URL)
+	ensure.Nil(t, err)
+	client := &fbapi.Client{BaseURL: base}
+	result, err := get(t, client, "342526215814610?fields=namespace")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, result["namespace"], "fbrell")
+}
+
+func TestLoadFixtureInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := fakegraph.LoadFixture("testdata/missing.json")
+	ensure.NotNil(t, err)
+	_, err = fakegraph.LoadFixture("fakegraph.go")
+	ensure.Err(t, err, regexp.MustCompile("Invalid fake Graph API fixture"))
+}
@@ -3,6 +3,7 @@ module github.com/fbsamples/fbrell
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/daaku/ctxerr v0.0.0-20160819070907-509c438ca2ac
 	github.com/daaku/ctxmux v0.0.0-20160816070229-fe5583754039
 	github.com/daaku/go.browserid v0.0.0-20150319225204-b9bd71b767c8
@@ -18,6 +19,7 @@ require (
 	github.com/daaku/go.trustforward v0.0.0-20150319220104-54ecc813bfdf
 	github.com/daaku/go.viewvar v0.0.0-20120507214831-4f967f8d6640
 	github.com/daaku/sortutil v0.0.0-20130919010756-68154d48a984
+	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a
 	github.com/facebookgo/counting v0.0.0-20150612182857-1d2e5f475a1f
 	github.com/facebookgo/devrestarter v0.0.0-20181024184655-8e7cc2e8cf11
 	github.com/facebookgo/ensure v0.0.0-20200202191622-63f1cf65ac4c
@@ -26,19 +28,20 @@ require (
 	github.com/facebookgo/flagenv v0.0.0-20160425205200-fcd59fca7456
 	github.com/facebookgo/httpcontrol v0.0.0-20150708234001-ccde4420e1fe
 	github.com/facebookgo/httpdown v0.0.0-20180706035922-5979d39b15c2
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da
+	golang.org/x/sync v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
 	github.com/facebookgo/freeport v0.0.0-20150612182905-d4adf43b75b9 // indirect
 	github.com/facebookgo/jsonpipe v0.0.0-20150612182908-8f89124372b6 // indirect
 	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
 	github.com/facebookgo/stackerr v0.0.0-20150612192056-c2fcf88613f4 // indirect
 	github.com/facebookgo/stats v0.0.0-20151006221625-1b76add642e4 // indirect
 	github.com/facebookgo/subset v0.0.0-20200203212716-c811ad88dec4 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/julienschmidt/httprouter v1.3.0 // indirect
 	golang.org/x/net v0.38.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
@@ -22,12 +22,18 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	browserid "github.com/daaku/go.browserid"
@@ -40,8 +46,11 @@ import (
 	"github.com/facebookgo/httpcontrol"
 	"github.com/facebookgo/httpdown"
 	"github.com/fbsamples/fbrell/adminweb"
+	"github.com/fbsamples/fbrell/cache"
 	"github.com/fbsamples/fbrell/examples"
 	"github.com/fbsamples/fbrell/examples/viewexamples"
+	"github.com/fbsamples/fbrell/fakegraph"
+	"github.com/fbsamples/fbrell/mockcanvas"
 	"github.com/fbsamples/fbrell/mockoauth"
 	"github.com/fbsamples/fbrell/mockpartner/capisetup"
 	"github.com/fbsamples/fbrell/mockpartner/jobseasyapply"
@@ -53,7 +62,7 @@ import (
 	"github.com/fbsamples/fbrell/rellenv/empcheck"
 	"github.com/fbsamples/fbrell/rellenv/viewcontext"
 	"github.com/fbsamples/fbrell/web"
-	"github.com/golang/groupcache/lru"
+	"gopkg.in/yaml.v3"
 )
 
 func defaultAddr() string {
@@ -63,6 +72,119 @@ func defaultAddr() string {
 	return ":43600"
 }
 
+// Lints the examples in sources, writing a JSON report to stdout. Returns the
+// exit status, which is non-zero if any issues were found.
+func lintExamples(sources []examples.Source) int {
+	var roots []string
+	for _, source := range sources {
+		roots = append(roots, source.Name)
+	}
+	var issues []*examples.LintIssue
+	db, err := examples.MakeLintDBFrom(sources...)
+	if err != nil {
+		issues = append(issues, &examples.LintIssue{
+			Check:   examples.LintLoad,
+			Message: err.Error(),
+		})
+	} else {
+		issues = examples.Lint(db)
+	}
+	report := struct {
+		Roots  []string              `json:"roots"`
+		Issues []*examples.LintIssue `json:"issues"`
+	}{
+		Roots:  roots,
+		Issues: issues,
+	}
+	if report.Issues == nil {
+		report.Issues = []*examples.LintIssue{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// The -empcheck flags.
+type empCheckFlags struct {
+	Policy     string
+	UIDs       empcheck.UserIDs
+	Domains    []string
+	Header     string
+	TrustProxy bool
+	AppSecret  string // -fb-app-secret, which verifies the signed request user
+}
+
+// Returns the employee checker for the -empcheck policy. The graph and
+// email-domain policies use the given Graph API checker. The uids and
+// email-domain policies trust the user in the signed request, so they need
+// the app secret to verify it. The header policy trusts anyone able to send
+// the header, so it must be explicitly told that a proxy strips it; domains
+// only narrow down who the proxy lets in.
+func newEmpChecker(f empCheckFlags, graph *empcheck.Checker) (rellenv.EmpChecker, error) {
+	switch f.Policy {
+	case "graph":
+		return graph, nil
+	case "email-domain":
+		if len(f.Domains) == 0 {
+			return nil, errors.New("-empcheck=email-domain requires -empcheck-domains")
+		}
+		if f.AppSecret == "" {
+			return nil, errors.New("-empcheck=email-domain requires -fb-app-secret")
+		}
+		graph.Domains = f.Domains
+		return graph, nil
+	case "uids":
+		if len(f.UIDs) == 0 {
+			return nil, errors.New("-empcheck=uids requires -empcheck-uids")
+		}
+		if f.AppSecret == "" {
+			return nil, errors.New("-empcheck=uids requires -fb-app-secret")
+		}
+		return f.UIDs, nil
+	case "header":
+		if f.Header == "" {
+			return nil, errors.New("-empcheck=header requires -empcheck-header")
+		}
+		if !f.TrustProxy {
+			return nil, errors.New("-empcheck=header requires -empcheck-trust-proxy")
+		}
+		return &empcheck.Header{Name: f.Header, Domains: f.Domains}, nil
+	}
+	return nil, fmt.Errorf(
+		"Unknown -empcheck %q, expected graph, email-domain, uids or header", f.Policy)
+}
+
+// Parses comma separated user ids.
+func parseUserIDs(s string) (empcheck.UserIDs, error) {
+	var ids empcheck.UserIDs
+	for _, raw := range splitList(s) {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("Invalid user id %q in -empcheck-uids", raw)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Splits a comma separated list, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 func main() {
 	const signedRequestMaxAge = time.Hour * 24
 
@@ -74,17 +196,84 @@ func main() {
 	facebookAppNS := flag.String("fb-app-ns", "", "facebook application namespace")
 	empCheckerAppID := flag.Uint64("empcheck-app-id", 0, "empcheck application id")
 	empCheckerAppSecret := flag.String("empcheck-app-secret", "", "empcheck application secret")
+	empCheckPolicy := flag.String(
+		"empcheck", "graph", "employee check: graph, email-domain, uids or header")
+	empCheckUIDs := flag.String(
+		"empcheck-uids", "", "comma separated employee user ids for -empcheck=uids")
+	empCheckDomains := flag.String(
+		"empcheck-domains", "",
+		"comma separated employee email domains for -empcheck=email-domain, "+
+			"or to further limit -empcheck=header")
+	empCheckHeader := flag.String(
+		"empcheck-header", "X-Forwarded-Email",
+		"header set by an authenticating proxy for -empcheck=header")
+	empCheckTrustProxy := flag.Bool(
+		"empcheck-trust-proxy", false,
+		"trust -empcheck-header, only safe behind a proxy which strips it from "+
+			"incoming requests; required by -empcheck=header")
 	publicDir := flag.String(
-		"public-dir", "./public", "public files directory")
+		"public-dir", "", "public files directory, defaults to the stock public files")
 	examplesDir := flag.String(
-		"examples-dir", "./examples/db", "example files directory")
+		"examples-dir", "",
+		"example files directories separated by "+string(filepath.ListSeparator)+
+			", later ones override earlier ones, defaults to the stock examples")
+	watchExamples := flag.Bool(
+		"watch-examples", false, "reload examples when files change, implied by -dev")
+	savedDir := flag.String(
+		"saved-dir", "./saved", "saved example files directory")
+	savedMaxBytes := flag.Uint64(
+		"saved-max-bytes", 100<<20,
+		"total size of the saved examples before saving is refused, 0 is unbounded")
+	fakeGraph := flag.String(
+		"fake-graph", "", "JSON fixture answering Graph API lookups instead of the network")
+	cacheFile := flag.String(
+		"cache-file", "",
+		"JSON file to keep the Graph API caches in across restarts, ignored with -fake-graph")
+	appsFile := flag.String(
+		"apps", "", "JSON file with additional apps offered in the app picker")
+	presetsFile := flag.String(
+		"presets", "", "JSON file with named env presets, selected by ?preset=name")
+	lint := flag.Bool(
+		"lint", false, "lint the examples in examples-dir and exit")
+	configFile := flag.String(
+		"config", "", "YAML, JSON or TOML config file, overridden by RELL_ env vars and flags")
+	printConfig := flag.Bool(
+		"print-config", false, "print the effective config with secrets redacted and exit")
 
 	flag.Parse()
+	config := &Config{}
+	if path := *configFile; path != "" || os.Getenv("RELL_CONFIG") != "" {
+		if path == "" {
+			path = os.Getenv("RELL_CONFIG")
+		}
+		var err error
+		if config, err = LoadConfig(path); err == nil {
+			err = config.Apply(flag.CommandLine)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
 	if err := flagenv.ParseSet("RELL_", flag.CommandLine); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
 	}
 
+	exampleSources, err := exampleSources(filepath.SplitList(*examplesDir))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *lint {
+		os.Exit(lintExamples(exampleSources))
+	}
+	publicFS, embeddedPublic, err := publicFileSystem(*publicDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	if *dev {
 		devrestarter.Init()
 	}
@@ -96,6 +285,61 @@ func main() {
 		logger.SetFlags(0)
 	}
 
+	presets := config.Presets
+	if *presetsFile != "" {
+		var err error
+		if presets, err = rellenv.LoadPresets(*presetsFile); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	apps, err := rellenv.NewApps(config.Apps)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if *appsFile != "" {
+		if apps, err = rellenv.LoadApps(*appsFile); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	empCheckUserIDs, err := parseUserIDs(*empCheckUIDs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if *printConfig {
+		effective := &Config{
+			Dev:                dev,
+			Addr:               addr,
+			AdminPath:          adminPath,
+			FbAppID:            facebookAppID,
+			FbAppSecret:        facebookAppSecret,
+			FbAppNS:            facebookAppNS,
+			EmpCheckAppID:      empCheckerAppID,
+			EmpCheckAppSecret:  empCheckerAppSecret,
+			EmpCheck:           empCheckPolicy,
+			EmpCheckUIDs:       empCheckUserIDs,
+			EmpCheckDomains:    splitList(*empCheckDomains),
+			EmpCheckHeader:     empCheckHeader,
+			EmpCheckTrustProxy: empCheckTrustProxy,
+			PublicDir:          publicDir,
+			ExamplesDir:        filepath.SplitList(*examplesDir),
+			WatchExamples:      watchExamples,
+			SavedDir:           savedDir,
+			SavedMaxBytes:      savedMaxBytes,
+			FakeGraph:          fakeGraph,
+			CacheFile:          cacheFile,
+			Apps:               apps.Configs(),
+			Presets:            presets,
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(effective.Redacted()); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
 	fbApp := fbapp.New(
 		*facebookAppID,
 		*facebookAppSecret,
@@ -112,7 +356,9 @@ func main() {
 		Logger:    logger,
 		Forwarded: forwarded,
 	}
-	publicFS := http.Dir(*publicDir)
+	if embeddedPublic {
+		logger.Printf("Using embedded public files")
+	}
 	static := &static.Handler{
 		Path: "/static/",
 		Box:  static.FileSystemBox(publicFS),
@@ -126,41 +372,120 @@ func main() {
 	fbApiClient := &fbapi.Client{
 		Transport: httpTransport,
 	}
-	lruCache := lru.New(10000)
-	empChecker := &empcheck.Checker{
-		FbApiClient: fbApiClient,
-		App:         fbapp.New(*empCheckerAppID, *empCheckerAppSecret, ""),
-		Logger:      logger,
-		Cache:       lruCache,
+	if *fakeGraph != "" {
+		fixture, err := fakegraph.LoadFixture(*fakeGraph)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		fbApiClient.Transport = &fakegraph.Server{Fixture: fixture}
+		logger.Printf("Using fake Graph API from %s", *fakeGraph)
+	}
+	empCheckCache := &cache.Cache[uint64, bool]{Name: "empcheck", Size: 10000}
+	appNSCache := &cache.Cache[uint64, string]{
+		Name: "appns",
+		Size: 10000,
+		TTL:  24 * time.Hour,
+	}
+	// Fake answers must not end up in, or be shadowed by, the real cache file.
+	var cacheStore *cache.FileStore
+	if *cacheFile != "" && *fakeGraph != "" {
+		logger.Printf("Ignoring -cache-file %s with -fake-graph", *cacheFile)
+	} else if *cacheFile != "" {
+		cacheStore = &cache.FileStore{Path: *cacheFile}
+		if err := cacheStore.Load(); err != nil {
+			logger.Printf("Ignoring error loading cache file: %s", err)
+		}
+		empCheckCache.Store = cacheStore
+		appNSCache.Store = cacheStore
+		go func() {
+			for range time.Tick(5 * time.Minute) {
+				if err := cacheStore.Save(); err != nil {
+					logger.Printf("Error saving cache file: %s", err)
+				}
+			}
+		}()
+	}
+	empChecker, err := newEmpChecker(
+		empCheckFlags{
+			Policy:     *empCheckPolicy,
+			UIDs:       empCheckUserIDs,
+			Domains:    splitList(*empCheckDomains),
+			Header:     *empCheckHeader,
+			TrustProxy: *empCheckTrustProxy,
+			AppSecret:  *facebookAppSecret,
+		},
+		&empcheck.Checker{
+			FbApiClient: fbApiClient,
+			App:         fbapp.New(*empCheckerAppID, *empCheckerAppSecret, ""),
+			Logger:      logger,
+			Cache:       empCheckCache,
+		},
+	)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	// the admin endpoints and stats are only for the Graph API checker
+	graphEmpChecker, _ := empChecker.(*empcheck.Checker)
+	if graphEmpChecker != nil {
+		expvar.Publish("empcheck", expvar.Func(func() interface{} {
+			return graphEmpChecker.Stats()
+		}))
 	}
 	appNSFetcher := &appns.Fetcher{
-		Apps:        []fbapp.App{fbApp},
 		FbApiClient: fbApiClient,
 		Logger:      logger,
-		Cache:       lruCache,
+		Cache:       appNSCache,
+	}
+	expvar.Publish("appns", expvar.Func(func() interface{} {
+		return appNSCache.Stats()
+	}))
+	for _, source := range exampleSources {
+		if source.Dir == "" {
+			logger.Printf("Using %s examples", source.Name)
+		}
+	}
+	examplesDB, err := examples.MakeDBFrom(exampleSources...)
+	if err != nil {
+		logger.Fatal(err)
 	}
-	exampleStore := &examples.Store{
-		DB: examples.MustMakeDB(*examplesDir),
+	exampleStore := examples.NewStore(examplesDB, &examples.DiskBackend{
+		Dir:      *savedDir,
+		MaxBytes: int64(*savedMaxBytes),
+	})
+	if *dev || *watchExamples {
+		examplesWatcher := &examples.Watcher{
+			Store:   exampleStore,
+			Sources: exampleSources,
+			Logger:  logger,
+		}
+		if err := examplesWatcher.Start(); err != nil {
+			logger.Fatal(err)
+		}
+		defer examplesWatcher.Close()
 	}
 	adminHandler := &adminweb.Handler{
-		Forwarded: forwarded,
-		Path:      *adminPath,
-		SkipHTTPS: *dev,
+		Forwarded:  forwarded,
+		Path:       *adminPath,
+		SkipHTTPS:  *dev,
+		EmpChecker: graphEmpChecker,
 	}
 	adminHandler.Init()
+	envParser := &rellenv.Parser{
+		App:                 fbApp,
+		EmpChecker:          empChecker,
+		AppNSFetcher:        appNSFetcher,
+		SignedRequestMaxAge: signedRequestMaxAge,
+		Forwarded:           forwarded,
+		Presets:             presets,
+		Apps:                apps,
+	}
+	appNSFetcher.Apps = envParser.AllApps().FbApps()
 	webHandler := &web.Handler{
-		Static: static,
-		App:    fbApp,
-		Logger: logger,
-		EnvParser: &rellenv.Parser{
-			App:                 fbApp,
-			EmpChecker:          empChecker,
-			AppNSFetcher:        appNSFetcher,
-			SignedRequestMaxAge: signedRequestMaxAge,
-			Forwarded:           forwarded,
-		},
+		Static:         static,
+		Logger:         logger,
+		EnvParser:      envParser,
 		PublicFS:       publicFS,
-		ContextHandler: &viewcontext.Handler{},
+		ContextHandler: &viewcontext.Handler{EnvParser: envParser},
 		ExamplesHandler: &viewexamples.Handler{
 			ExampleStore: exampleStore,
 			Static:       static,
@@ -181,6 +506,9 @@ func main() {
 		AdminHandler:         adminHandler,
 		SignedRequestMaxAge:  signedRequestMaxAge,
 	}
+	if *dev {
+		webHandler.MockCanvasHandler = &mockcanvas.Handler{EnvParser: envParser}
+	}
 	if err := webHandler.Init(); err != nil {
 		logger.Fatal(err)
 	}
@@ -198,4 +526,9 @@ func main() {
 	if err := httpdown.ListenAndServe(httpServer, hdConfig); err != nil {
 		logger.Fatal(err)
 	}
+	if cacheStore != nil {
+		if err := cacheStore.Save(); err != nil {
+			logger.Printf("Error saving cache file: %s", err)
+		}
+	}
 }
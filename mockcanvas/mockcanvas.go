@@ -0,0 +1,339 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package mockcanvas mints signed requests the way facebook.com does when it
+// loads a Canvas app or Page Tab, so those view modes can be tested locally.
+//
+// Two endpoints are served, and should only be enabled in development since
+// anyone can use them to sign arbitrary requests with the app secret:
+//   - GET /mock-canvas/ — renders a form to choose the signed request fields.
+//   - POST /mock-canvas/sign — signs the request and POSTs it to the target
+//     fbrell route as the signed_request parameter.
+package mockcanvas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daaku/go.h"
+	"github.com/daaku/go.signedrequest/appdata"
+	"github.com/fbsamples/fbrell/errcode"
+	"github.com/fbsamples/fbrell/rellenv"
+)
+
+const (
+	Path = "/mock-canvas/"
+
+	algorithm = "HMAC-SHA256"
+)
+
+// Request describes the signed request to mint.
+type Request struct {
+	Target    string        // the fbrell route to POST to
+	PageTab   bool          // include a page, making it a Page Tab request
+	UserID    uint64        // the user, or zero for a logged out user
+	PageID    uint64        // the page for Page Tab requests
+	PageAdmin bool          // the user is an admin of the page
+	PageLiked bool          // the user likes the page
+	Locale    string        // the user locale
+	Country   string        // the user country
+	AgeMin    uint          // the minimum of the user age range
+	Age       time.Duration // how long ago the signed request was issued
+	AppData   string        // a URL path, or a raw app_data value
+}
+
+// ParseRequest parses the Request from the form values.
+func ParseRequest(r *http.Request) (*Request, error) {
+	q := &Request{
+		Target:    r.FormValue("target"),
+		PageTab:   r.FormValue("mode") == rellenv.PageTab,
+		PageAdmin: r.FormValue("page_admin") != "",
+		PageLiked: r.FormValue("page_liked") != "",
+		Locale:    r.FormValue("locale"),
+		Country:   r.FormValue("country"),
+		AppData:   r.FormValue("app_data"),
+	}
+	if q.Target == "" {
+		q.Target = "/"
+	}
+	if !strings.HasPrefix(q.Target, "/") || strings.HasPrefix(q.Target, "//") ||
+		strings.Contains(q.Target, "\\") {
+		return nil, errcode.New(http.StatusBadRequest,
+			"mock-canvas: target must be a local path, got %q", q.Target)
+	}
+	var err error
+	if q.UserID, err = parseUint(r, "user_id"); err != nil {
+		return nil, err
+	}
+	if q.PageID, err = parseUint(r, "page_id"); err != nil {
+		return nil, err
+	}
+	ageMin, err := parseUint(r, "age_min")
+	if err != nil {
+		return nil, err
+	}
+	q.AgeMin = uint(ageMin)
+	if age := r.FormValue("age"); age != "" {
+		if q.Age, err = time.ParseDuration(age); err != nil {
+			return nil, errcode.New(http.StatusBadRequest,
+				"mock-canvas: invalid age %q, expected a duration like 90s or 25h", age)
+		}
+	}
+	if q.PageTab && q.PageID == 0 {
+		return nil, errcode.New(http.StatusBadRequest,
+			"mock-canvas: page_id is required for page tab requests")
+	}
+	return q, nil
+}
+
+func parseUint(r *http.Request, name string) (uint64, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errcode.New(http.StatusBadRequest,
+			"mock-canvas: invalid %s %q, expected a number", name, value)
+	}
+	return n, nil
+}
+
+// Payload returns the signed request payload, in the format used by
+// facebook.com, issued Age before now.
+func (q *Request) Payload(now time.Time) map[string]interface{} {
+	payload := map[string]interface{}{
+		"algorithm": algorithm,
+		"issued_at": now.Add(-q.Age).Unix(),
+	}
+	if q.UserID != 0 {
+		payload["user_id"] = strconv.FormatUint(q.UserID, 10)
+	}
+	user := map[string]interface{}{}
+	if q.Locale != "" {
+		user["locale"] = q.Locale
+	}
+	if q.Country != "" {
+		user["country"] = q.Country
+	}
+	if q.AgeMin != 0 {
+		user["age"] = map[string]interface{}{"min": q.AgeMin}
+	}
+	if len(user) > 0 {
+		payload["user"] = user
+	}
+	if q.PageTab {
+		payload["page"] = map[string]interface{}{
+			"id":    strconv.FormatUint(q.PageID, 10),
+			"admin": q.PageAdmin,
+			"liked": q.PageLiked,
+		}
+	}
+	if q.AppData != "" {
+		payload["app_data"] = encodeAppData(q.AppData)
+	}
+	return payload
+}
+
+// URL paths are encoded like Page Tab links, other values are used as is to
+// allow testing invalid app_data.
+func encodeAppData(value string) string {
+	if strings.HasPrefix(value, "/") {
+		if u, err := url.ParseRequestURI(value); err == nil {
+			return appdata.Encode(u)
+		}
+	}
+	return value
+}
+
+// Sign returns the signed request for the payload.
+func Sign(payload interface{}, secret []byte) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) + "." + encoded, nil
+}
+
+// Handler serves the signed request generator.
+type Handler struct {
+	EnvParser *rellenv.Parser
+}
+
+// Handle routes requests to the appropriate mock canvas endpoint.
+func (a *Handler) Handle(w http.ResponseWriter, r *http.Request) error {
+	switch r.URL.Path {
+	case Path:
+		return a.Form(w, r)
+	case Path + "sign":
+		if r.Method != http.MethodPost {
+			return errcode.New(http.StatusMethodNotAllowed,
+				"mock-canvas: sign endpoint requires POST")
+		}
+		return a.Submit(w, r)
+	default:
+		return errcode.New(http.StatusNotFound,
+			"No mock-canvas endpoint at %s", r.URL.Path)
+	}
+}
+
+// Form handles GET /mock-canvas/. Fields are prefilled from the query.
+func (a *Handler) Form(w http.ResponseWriter, r *http.Request) error {
+	mode := r.FormValue("mode")
+	target := r.FormValue("target")
+	if target == "" {
+		target = "/"
+	}
+	locale := r.FormValue("locale")
+	if locale == "" {
+		locale = rellenv.DefaultLocale
+	}
+	return writePage(w, r, "Mock Canvas", &h.Form{
+		Method: "post",
+		Action: Path + "sign",
+		Inner: h.Frag{
+			field("Target route", &h.Input{Name: "target", Value: target}),
+			field("Mode", &h.Select{Name: "mode", Inner: h.Frag{
+				&h.Option{Value: rellenv.Canvas, Inner: h.String("Canvas"),
+					Selected: mode != rellenv.PageTab},
+				&h.Option{Value: rellenv.PageTab, Inner: h.String("Page Tab"),
+					Selected: mode == rellenv.PageTab},
+			}}),
+			field("User ID", &h.Input{Name: "user_id", Value: r.FormValue("user_id"),
+				Placeholder: "logged out"}),
+			field("Page ID", &h.Input{Name: "page_id", Value: r.FormValue("page_id"),
+				Placeholder: "required for Page Tab"}),
+			checkbox("Page admin", "page_admin", r.FormValue("page_admin") != ""),
+			checkbox("Page liked", "page_liked", r.FormValue("page_liked") != ""),
+			field("Locale", &h.Input{Name: "locale", Value: locale}),
+			field("Country", &h.Input{Name: "country", Value: r.FormValue("country"),
+				Placeholder: "us"}),
+			field("Minimum user age", &h.Input{Name: "age_min", Value: r.FormValue("age_min"),
+				Placeholder: "21"}),
+			field("Issued ago", &h.Input{Name: "age", Value: r.FormValue("age"),
+				Placeholder: "0s, or 25h to test expiry"}),
+			field("app_data", &h.Input{Name: "app_data", Value: r.FormValue("app_data"),
+				Placeholder: "/examples/"}),
+			&h.Div{Class: "actions", Inner: &h.Node{Tag: "button", Attributes: h.Attributes{
+				"type": "submit", "class": "btn btn-primary",
+			}, Inner: h.String("Sign & Post")}},
+		},
+	})
+}
+
+// Submit handles POST /mock-canvas/sign. It renders a form which POSTs the
+// signed request to the target, and submits it immediately.
+func (a *Handler) Submit(w http.ResponseWriter, r *http.Request) error {
+	q, err := ParseRequest(r)
+	if err != nil {
+		return err
+	}
+	// sign with the secret of the app the target will select
+	target, err := http.NewRequest("GET", q.Target, nil)
+	if err != nil {
+		return errcode.Add(http.StatusBadRequest, err)
+	}
+	for _, cookie := range r.Cookies() {
+		target.AddCookie(cookie)
+	}
+	secret := a.EnvParser.SignedRequestSecret(target)
+	if len(secret) == 0 {
+		return errcode.New(http.StatusBadRequest,
+			"The app selected by %s has no secret to sign the request with.", q.Target)
+	}
+	sr, err := Sign(q.Payload(time.Now()), secret)
+	if err != nil {
+		return err
+	}
+	return writePage(w, r, "Posting to "+q.Target, h.Frag{
+		&h.Form{
+			ID:     "mock-canvas-post",
+			Method: "post",
+			Action: q.Target,
+			Inner: h.Frag{
+				&h.Input{Type: "hidden", Name: "signed_request", Value: sr},
+				&h.Textarea{Class: "signed-request", Inner: h.String(sr)},
+				&h.Div{Class: "actions", Inner: h.Frag{
+					&h.Node{Tag: "button", Attributes: h.Attributes{
+						"type": "submit", "class": "btn btn-primary",
+					}, Inner: h.String("Post again")},
+					&h.Node{Tag: "button", Attributes: h.Attributes{
+						"type": "submit", "class": "btn btn-secondary",
+						"formaction": "/info/signed-request",
+					}, Inner: h.String("Diagnose")},
+				}},
+			},
+		},
+		&h.Script{Inner: h.Unsafe(
+			"document.getElementById('mock-canvas-post').submit()")},
+	})
+}
+
+func field(label string, input h.HTML) h.HTML {
+	return &h.Label{Class: "field", Inner: h.Frag{
+		&h.Span{Inner: h.String(label)},
+		input,
+	}}
+}
+
+func checkbox(label, name string, checked bool) h.HTML {
+	return &h.Label{Class: "field field-checkbox", Inner: h.Frag{
+		&h.Input{Type: "checkbox", Name: name, Value: "1", Checked: checked},
+		&h.Span{Inner: h.String(label)},
+	}}
+}
+
+func writePage(w http.ResponseWriter, r *http.Request, title string, body h.HTML) error {
+	page := &h.Document{
+		Lang: "en",
+		Inner: h.Frag{
+			&h.Head{Inner: h.Frag{
+				&h.Meta{Charset: "utf-8"},
+				&h.Node{Tag: "meta", Attributes: h.Attributes{
+					"name": "viewport", "content": "width=device-width, initial-scale=1",
+				}, SelfClosing: true},
+				&h.Node{Tag: "title", Inner: h.String(title + " — fbrell")},
+				&h.Link{Rel: "stylesheet", Type: "text/css", HREF: "/public/css/mockcanvas.css"},
+			}},
+			&h.Body{Inner: &h.Div{Class: "card", Inner: h.Frag{
+				&h.Div{Class: "header", Inner: h.Frag{
+					&h.H1{Inner: h.String(title)},
+					&h.Div{Class: "subtitle", Inner: h.String("Signed request generator")},
+				}},
+				&h.Div{Class: "body", Inner: body},
+				&h.Div{Class: "mock-badge",
+					Inner: h.String("⚠ Development only. Requests are signed with the app secret.")},
+			}}},
+		},
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := h.Write(r.Context(), w, page)
+	return err
+}
@@ -0,0 +1,186 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package mockcanvas_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/go.signedrequest/appdata"
+	"github.com/daaku/go.trustforward"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/mockcanvas"
+	"github.com/fbsamples/fbrell/rellenv"
+)
+
+const secret = "secret"
+
+type funcEmpChecker func(uint64) bool
+
+func (f funcEmpChecker) Check(ctx context.Context, uid uint64) bool {
+	return f(uid)
+}
+
+type funcAppNSFetcher func(uint64) string
+
+func (f funcAppNSFetcher) Get(ctx context.Context, id uint64) string {
+	return f(id)
+}
+
+func parser() *rellenv.Parser {
+	return &rellenv.Parser{
+		EmpChecker:          funcEmpChecker(func(uint64) bool { return false }),
+		AppNSFetcher:        funcAppNSFetcher(func(uint64) string { return "" }),
+		App:                 fbapp.New(42, secret, ""),
+		Forwarded:           &trustforward.Forwarded{},
+		SignedRequestMaxAge: time.Hour,
+	}
+}
+
+func signRequest(t *testing.T, form url.Values) *http.Request {
+	req := httptest.NewRequest("POST", mockcanvas.Path+"sign", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	q, err := mockcanvas.ParseRequest(req)
+	ensure.Nil(t, err)
+	sr, err := mockcanvas.Sign(q.Payload(time.Now()), []byte(secret))
+	ensure.Nil(t, err)
+	post := httptest.NewRequest("POST", q.Target, nil)
+	post.Form = url.Values{"signed_request": []string{sr}}
+	return post
+}
+
+func TestCanvas(t *testing.T) {
+	t.Parallel()
+	req := signRequest(t, url.Values{
+		"user_id": []string{"4"},
+		"locale":  []string{"fr_FR"},
+		"age_min": []string{"21"},
+	})
+	env, err := parser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Canvas)
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+	ensure.DeepEqual(t, env.SignedRequest.UserID, uint64(4))
+	ensure.DeepEqual(t, env.SignedRequest.User.Locale, "fr_FR")
+	ensure.DeepEqual(t, env.SignedRequest.User.Age.Min, uint(21))
+}
+
+func TestPageTab(t *testing.T) {
+	t.Parallel()
+	req := signRequest(t, url.Values{
+		"mode":       []string{rellenv.PageTab},
+		"page_id":    []string{"123"},
+		"page_admin": []string{"1"},
+		"app_data":   []string{"/examples/?status=1"},
+	})
+	env, err := parser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.PageTab)
+	ensure.DeepEqual(t, env.SignedRequest.Page.ID, uint64(123))
+	ensure.True(t, env.SignedRequest.Page.Admin)
+	ensure.False(t, env.SignedRequest.Page.Liked)
+	u, err := appdata.Decode(env.SignedRequest.AppData)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, u.String(), "/examples/?status=1")
+}
+
+func TestExpired(t *testing.T) {
+	t.Parallel()
+	req := signRequest(t, url.Values{"age": []string{"2h"}})
+	env, err := parser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Website)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+}
+
+func TestParseRequestInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]url.Values{
+		"target must be a local path": {"target": []string{"//evil.com/"}},
+		"invalid user_id":             {"user_id": []string{"me"}},
+		"invalid age":                 {"age": []string{"old"}},
+		"page_id is required":         {"mode": []string{rellenv.PageTab}},
+	}
+	for msg, form := range cases {
+		req := httptest.NewRequest("GET", mockcanvas.Path+"sign?"+form.Encode(), nil)
+		_, err := mockcanvas.ParseRequest(req)
+		ensure.Err(t, err, regexp.MustCompile(msg))
+	}
+}
+
+func TestSubmitUsesTargetAppSecret(t *testing.T) {
+	t.Parallel()
+	p := parser()
+	p.Apps = rellenv.Apps{{App: fbapp.New(43, "other", ""), Label: "Other"}}
+	h := &mockcanvas.Handler{EnvParser: p}
+	req := httptest.NewRequest("POST", mockcanvas.Path+"sign",
+		strings.NewReader("target=/info/%3Fappid%3D43"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, h.Handle(w, req))
+	sr := regexp.MustCompile(`name="signed_request" type="hidden" value="([^"]+)"`).
+		FindStringSubmatch(w.Body.String())
+	ensure.DeepEqual(t, len(sr), 2)
+
+	post := httptest.NewRequest("POST", "/info/?appid=43", nil)
+	post.Form = url.Values{"appid": []string{"43"}, "signed_request": []string{sr[1]}}
+	env, err := p.FromRequest(post)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Canvas)
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+}
+
+func TestSubmitNoSecret(t *testing.T) {
+	t.Parallel()
+	p := parser()
+	p.Apps = rellenv.Apps{{App: fbapp.New(44, "", ""), Label: "No Secret"}}
+	h := &mockcanvas.Handler{EnvParser: p}
+	req := httptest.NewRequest("POST", mockcanvas.Path+"sign",
+		strings.NewReader("target=/info/%3Fappid%3D44"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ensure.Err(t, h.Handle(httptest.NewRecorder(), req), regexp.MustCompile("no secret"))
+}
+
+func TestSubmitRendersPostForm(t *testing.T) {
+	t.Parallel()
+	h := &mockcanvas.Handler{EnvParser: parser()}
+	req := httptest.NewRequest("POST", mockcanvas.Path+"sign",
+		strings.NewReader("target=/examples/"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, h.Handle(w, req))
+	ensure.StringContains(t, w.Body.String(), `action="/examples/"`)
+	ensure.StringContains(t, w.Body.String(), `name="signed_request"`)
+}
+
+func TestSignRequiresPost(t *testing.T) {
+	t.Parallel()
+	h := &mockcanvas.Handler{EnvParser: parser()}
+	req := httptest.NewRequest("GET", mockcanvas.Path+"sign", nil)
+	ensure.Err(t, h.Handle(httptest.NewRecorder(), req), regexp.MustCompile("requires POST"))
+}
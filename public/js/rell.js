@@ -92,9 +92,11 @@ var Rell = {
 
     // Bind click handlers (vanilla JS)
     Rell.bindClick('rell-run-code', Rell.runCode);
+    Rell.bindClick('rell-save-code', Rell.saveCode);
     Rell.bindClick('rell-log-clear', Rell.clearLog);
     Rell.bindClick('rell-disconnect', Rell.disconnect);
     Rell.bindClick('fb-login-custom', Rell.loginToggle);
+    Rell.bindClick('rell-warnings-dismiss', Rell.dismissWarnings);
 
     // Initialize settings drawer
     Rell.initSettings();
@@ -154,18 +156,7 @@ var Rell = {
     };
 
     if (window.rellConfig.fedcm) {
-      if (window.rellConfig.fedcmAutoPrompt || window.rellConfig.fedcmContext) {
-        var fedcmOptions = {};
-        if (window.rellConfig.fedcmAutoPrompt) {
-          fedcmOptions.autoPrompt = true;
-        }
-        if (window.rellConfig.fedcmContext) {
-          fedcmOptions.context = window.rellConfig.fedcmContext;
-        }
-        options.fedCM = fedcmOptions;
-      } else {
-        options.fedCM = true;
-      }
+      options.fedCM = Rell.fedcmOptions(window.rellConfig);
     }
 
     // NOTE: Do NOT strip the URL hash before FB.init(). On mobile Safari,
@@ -205,6 +196,35 @@ var Rell = {
     Rell.updateStatusBar();
   },
 
+  /**
+   * Build the FB.init() fedCM option from the config. The server validates
+   * the values, so they are passed through as is.
+   * @param {Object} config - window.rellConfig
+   * @returns {Object|boolean} FedCM options, or true for the defaults
+   */
+  fedcmOptions: function(config) {
+    var fedcmOptions = {};
+    var any = false;
+    var fields = {
+      fedcmContext: 'context',
+      fedcmMode: 'mode',
+      fedcmLoginHint: 'loginHint',
+      fedcmDomainHint: 'domainHint',
+      fedcmNonce: 'nonce'
+    };
+    if (config.fedcmAutoPrompt) {
+      fedcmOptions.autoPrompt = true;
+      any = true;
+    }
+    Object.keys(fields).forEach(function(name) {
+      if (config[name]) {
+        fedcmOptions[fields[name]] = config[name];
+        any = true;
+      }
+    });
+    return any ? fedcmOptions : true;
+  },
+
   /**
    * Bind a click handler to an element by ID, preventing default.
    * @param {string} id - Element ID
@@ -362,6 +382,24 @@ var Rell = {
     }
   },
 
+  /**
+   * Save the code from the editor and navigate to its shareable URL.
+   * Submits a form so the server can redirect to /saved/<id>, keeping the
+   * current query string so the settings carry over.
+   */
+  saveCode: function() {
+    var form = document.createElement('form');
+    form.method = 'POST';
+    form.action = '/saved/' + window.location.search;
+    var input = document.createElement('input');
+    input.type = 'hidden';
+    input.name = 'code';
+    input.value = Rell.getCode();
+    form.appendChild(input);
+    document.body.appendChild(form);
+    form.submit();
+  },
+
   /**
    * Get the current code from the editor (CodeMirror or textarea fallback).
    * @returns {string} The code string
@@ -461,7 +499,11 @@ var Rell = {
       customLogin: 'true',
       fedcm: 'false',
       fedcmAutoPrompt: 'false',
-      fedcmContext: ''
+      fedcmContext: '',
+      fedcmMode: '',
+      fedcmLoginHint: '',
+      fedcmDomainHint: '',
+      fedcmNonce: ''
     };
 
     /**
@@ -479,7 +521,9 @@ var Rell = {
         } else {
           val = el.value;
         }
-        if (val !== '' && val !== defaults[name]) {
+        var def = el.tagName === 'SELECT' && el.dataset.default !== undefined ?
+          el.dataset.default : defaults[name];
+        if (val !== '' && val !== def) {
           params.push(encodeURIComponent(name) + '=' + encodeURIComponent(val));
         }
       });
@@ -501,11 +545,35 @@ var Rell = {
       el.addEventListener('input', updatePreview);
     });
 
-    // Update button navigates to the built URL
+    // Update button navigates to the built URL. When remembering, the
+    // settings are stored in a cookie instead, and unchecking remember
+    // forgets the stored settings.
     var updateBtn = document.getElementById('rell-settings-update');
+    var remember = document.getElementById('rell-settings-remember');
     if (updateBtn) {
       updateBtn.addEventListener('click', function() {
-        window.location = buildSettingsUrl();
+        var url = buildSettingsUrl();
+        // the settings endpoints only accept POSTs
+        var post = function(action, nextURL) {
+          var form = document.createElement('form');
+          form.method = 'POST';
+          form.action = action;
+          var next = document.createElement('input');
+          next.type = 'hidden';
+          next.name = 'next';
+          next.value = nextURL;
+          form.appendChild(next);
+          document.body.appendChild(form);
+          form.submit();
+        };
+        if (remember && remember.checked) {
+          post('/settings/remember' + url.slice(window.location.pathname.length),
+            window.location.pathname);
+        } else if (remember && remember.defaultChecked) {
+          post('/settings/reset', url);
+        } else {
+          window.location = url;
+        }
       });
     }
   },
@@ -602,6 +670,14 @@ var Rell = {
   clearLog: function() {
     Log.clear();
     return false;
+  },
+
+  /**
+   * Remove the banner listing ignored or replaced settings.
+   */
+  dismissWarnings: function() {
+    var el = document.getElementById('rell-warnings');
+    if (el) el.parentNode.removeChild(el);
   }
 };
 
@@ -3,7 +3,7 @@
  *
  * The sidebar HTML is rendered server-side. This script adds interactive behavior:
  *   - Collapsible category sections
- *   - Live search/filter of examples
+ *   - Live search/filter of examples, Enter searches on the server
  *   - Active-state highlighting based on current URL
  *   - Mobile sidebar toggle with backdrop overlay
  *   - Persisted collapsed state via localStorage
@@ -52,7 +52,8 @@ var Sidebar = {
         var categories = sidebar.querySelectorAll('.sidebar-category');
 
         items.forEach(function(item) {
-          var match = !query || item.textContent.toLowerCase().indexOf(query) !== -1;
+          var text = item.textContent + ' ' + (item.dataset.tags || '');
+          var match = !query || text.toLowerCase().indexOf(query) !== -1;
           item.style.display = match ? '' : 'none';
         });
 
@@ -66,6 +67,14 @@ var Sidebar = {
           if (query) cat.classList.remove('collapsed');
         });
       });
+
+      // Enter runs a full-text search across all examples on the server
+      search.addEventListener('keydown', function(e) {
+        if (e.key !== 'Enter' || !this.value.trim()) return;
+        var params = new URLSearchParams(window.location.search);
+        params.set('q', this.value.trim());
+        window.location = '/examples/search?' + params.toString();
+      });
     }
 
     // Highlight the active example based on current path
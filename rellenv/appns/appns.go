@@ -23,17 +23,20 @@
 package appns
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
-	"sync"
+	"time"
 
 	"github.com/facebookgo/fbapi"
 	"github.com/facebookgo/fbapp"
-	"github.com/golang/groupcache/lru"
+	"github.com/fbsamples/fbrell/cache"
+	"golang.org/x/sync/singleflight"
 )
 
-type cacheKey uint64
+// DefaultTimeout bounds a Graph API lookup when Timeout is not set.
+const DefaultTimeout = 5 * time.Second
 
 type Logger interface {
 	Printf(format string, v ...interface{})
@@ -43,38 +46,59 @@ type Fetcher struct {
 	FbApiClient *fbapi.Client
 	Apps        []fbapp.App
 	Logger      Logger
-	Cache       *lru.Cache
-	mu          sync.Mutex
+	Cache       *cache.Cache[uint64, string]
+	Timeout     time.Duration // defaults to DefaultTimeout
+	group       singleflight.Group
 }
 
 // Get the App Namespace, fetching it using the Graph API if necessary.
-func (c *Fetcher) Get(id uint64) string {
+// Concurrent fetches for the same app share one Graph API call. If ctx is
+// done first, an empty namespace is returned while the fetch continues for
+// the others.
+func (c *Fetcher) Get(ctx context.Context, id uint64) string {
 	for _, app := range c.Apps {
 		if app.ID() == id {
 			return app.Namespace()
 		}
 	}
 
-	c.mu.Lock()
-	ns, ok := c.Cache.Get(cacheKey(id))
-	c.mu.Unlock()
-	if ok {
-		return ns.(string)
+	if ns, ok := c.Cache.Get(id); ok {
+		return ns
 	}
 
+	key := strconv.FormatUint(id, 10)
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		return c.fetch(id), nil
+	})
+	select {
+	case res := <-ch:
+		return res.Val.(string)
+	case <-ctx.Done():
+		return ""
+	}
+}
+
+// Fetches the namespace and caches it. The fetch is not bound to any one
+// request, since its result may be shared.
+func (c *Fetcher) fetch(id uint64) string {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
 	res := struct{ Namespace string }{""}
 	req := http.Request{
 		Method: "GET",
 		URL:    &url.URL{Path: strconv.FormatUint(id, 10)},
 	}
-	_, err := c.FbApiClient.Do(&req, &res)
+	_, err := c.FbApiClient.Do(req.WithContext(ctx), &res)
 	if err != nil {
 		c.Logger.Printf("Ignoring error API call for AppNamespace: %s", err)
 		return ""
 	}
 
-	c.mu.Lock()
-	c.Cache.Add(cacheKey(id), res.Namespace)
-	c.mu.Unlock()
+	c.Cache.Add(id, res.Namespace)
 	return res.Namespace
 }
@@ -0,0 +1,131 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package appns_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapi"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/cache"
+	"github.com/fbsamples/fbrell/rellenv/appns"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+var ctx = context.Background()
+
+// Responds with the namespace and counts the requests. Responses wait for
+// block to be closed or the request to be cancelled.
+type graph struct {
+	block    chan struct{}
+	mu       sync.Mutex
+	requests int
+}
+
+func (g *graph) RoundTrip(r *http.Request) (*http.Response, error) {
+	g.mu.Lock()
+	g.requests++
+	g.mu.Unlock()
+	select {
+	case <-g.block:
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"namespace":"rell"}`)),
+		Request:    r,
+	}, nil
+}
+
+func newFetcher(g *graph) *appns.Fetcher {
+	return &appns.Fetcher{
+		FbApiClient: &fbapi.Client{Transport: g},
+		Logger:      discardLogger{},
+		Cache:       &cache.Cache[uint64, string]{Size: 10},
+	}
+}
+
+func TestGetConfiguredApp(t *testing.T) {
+	g := &graph{}
+	fetcher := newFetcher(g)
+	fetcher.Apps = []fbapp.App{fbapp.New(42, "", "configured")}
+	ensure.DeepEqual(t, fetcher.Get(ctx, 42), "configured")
+	ensure.DeepEqual(t, g.requests, 0)
+}
+
+func TestGetCoalesced(t *testing.T) {
+	g := &graph{block: make(chan struct{})}
+	fetcher := newFetcher(g)
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = fetcher.Get(ctx, 42)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(g.block)
+	wg.Wait()
+	for _, ns := range results {
+		ensure.DeepEqual(t, ns, "rell")
+	}
+	ensure.DeepEqual(t, g.requests, 1)
+
+	// later calls are answered from the cache
+	ensure.DeepEqual(t, fetcher.Get(ctx, 42), "rell")
+	ensure.DeepEqual(t, g.requests, 1)
+}
+
+func TestGetCancelled(t *testing.T) {
+	g := &graph{block: make(chan struct{})}
+	fetcher := newFetcher(g)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	ensure.DeepEqual(t, fetcher.Get(cctx, 42), "")
+
+	// the lookup continues for other requests
+	close(g.block)
+	ensure.DeepEqual(t, fetcher.Get(ctx, 42), "rell")
+	ensure.DeepEqual(t, g.requests, 1)
+}
+
+func TestGetTimeout(t *testing.T) {
+	g := &graph{block: make(chan struct{})}
+	fetcher := newFetcher(g)
+	fetcher.Timeout = 10 * time.Millisecond
+	ensure.DeepEqual(t, fetcher.Get(ctx, 42), "")
+	_, cached := fetcher.Cache.Get(42)
+	ensure.False(t, cached)
+}
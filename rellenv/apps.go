@@ -0,0 +1,189 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/facebookgo/fbapp"
+)
+
+// App is a Facebook application along with a label for the app picker.
+type App struct {
+	fbapp.App
+	Label string
+}
+
+// Apps are the Facebook applications rell knows the secrets for, and offers
+// in the app picker.
+type Apps []App
+
+// Find returns the app with the given ID.
+func (as Apps) Find(id uint64) (App, bool) {
+	for _, app := range as {
+		if app.ID() == id {
+			return app, true
+		}
+	}
+	return App{}, false
+}
+
+// FbApps returns the apps as fbapp.Apps.
+func (as Apps) FbApps() []fbapp.App {
+	apps := make([]fbapp.App, 0, len(as))
+	for _, app := range as {
+		apps = append(apps, app.App)
+	}
+	return apps
+}
+
+// AppConfig describes an app in a configuration file.
+type AppConfig struct {
+	ID        uint64 `json:"id" yaml:"id" toml:"id"`
+	Secret    string `json:"secret,omitempty" yaml:"secret,omitempty" toml:"secret,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty" toml:"namespace,omitempty"`
+	Label     string `json:"label,omitempty" yaml:"label,omitempty" toml:"label,omitempty"`
+}
+
+// NewApps validates the app configurations and creates the Apps.
+func NewApps(configs []AppConfig) (Apps, error) {
+	var apps Apps
+	for i, c := range configs {
+		if c.ID == 0 {
+			return nil, fmt.Errorf("app %d is missing an id", i)
+		}
+		if _, ok := apps.Find(c.ID); ok {
+			return nil, fmt.Errorf("duplicate app %d", c.ID)
+		}
+		apps = append(apps, App{
+			App:   fbapp.New(c.ID, c.Secret, c.Namespace),
+			Label: c.Label,
+		})
+	}
+	return apps, nil
+}
+
+// LoadApps reads apps from a JSON file listing them:
+//
+//	[{"id": 123, "secret": "...", "namespace": "myapp", "label": "My App"}]
+func LoadApps(path string) (Apps, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read apps %s: %s", path, err)
+	}
+	var configs []AppConfig
+	if err := json.Unmarshal(content, &configs); err != nil {
+		return nil, fmt.Errorf("Invalid apps %s: %s", path, err)
+	}
+	apps, err := NewApps(configs)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid apps %s: %s", path, err)
+	}
+	return apps, nil
+}
+
+// Configs returns the configuration for the apps.
+func (as Apps) Configs() []AppConfig {
+	configs := make([]AppConfig, 0, len(as))
+	for _, app := range as {
+		configs = append(configs, AppConfig{
+			ID:        app.ID(),
+			Secret:    app.Secret(),
+			Namespace: app.Namespace(),
+			Label:     app.Label,
+		})
+	}
+	return configs
+}
+
+// AllApps returns the default app followed by the other configured apps.
+func (p *Parser) AllApps() Apps {
+	def, ok := p.Apps.Find(p.App.ID())
+	if !ok {
+		def = App{App: p.App}
+	}
+	apps := Apps{def}
+	for _, app := range p.Apps {
+		if app.ID() != def.ID() {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+// Returns the app with the given ID, or the default app if it is unknown.
+// The configured apps take precedence, including for the default app ID.
+func (p *Parser) app(id uint64) fbapp.App {
+	if app, ok := p.Apps.Find(id); ok {
+		return app.App
+	}
+	return p.App
+}
+
+// SignedRequestSecret returns the secret to verify signed requests with for
+// the app selected by the request.
+func (p *Parser) SignedRequestSecret(r *http.Request) []byte {
+	return p.app(p.requestAppID(r)).SecretByte()
+}
+
+// Returns the app ID selected by the request, with the same precedence as
+// layered but without applying the other settings: the URL, then the preset
+// and then the remembered settings.
+func (p *Parser) requestAppID(r *http.Request) uint64 {
+	find := func(get func(string) string) (uint64, bool) {
+		// client_id is applied after appid, so it wins
+		for _, name := range []string{"client_id", "appid"} {
+			if id, err := strconv.ParseUint(get(name), 10, 64); err == nil {
+				return id, true
+			}
+		}
+		return 0, false
+	}
+	if id, ok := find(r.FormValue); ok {
+		return id
+	}
+	if preset, ok := p.Presets[r.FormValue("preset")]; ok {
+		if id, ok := find(preset.Get); ok {
+			return id
+		}
+	}
+	if settings, err := p.readSettingsCookie(r); err == nil && settings != nil {
+		if id, ok := find(settings.Get); ok {
+			return id
+		}
+	}
+	return p.App.ID()
+}
+
+// Name returns the label for the app, or its ID when it has none.
+func (a App) Name() string {
+	if a.Label != "" {
+		return a.Label
+	}
+	if a.Namespace() != "" {
+		return a.Namespace()
+	}
+	return strconv.FormatUint(a.ID(), 10)
+}
@@ -0,0 +1,150 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv_test
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/rellenv"
+)
+
+func appsParser() *rellenv.Parser {
+	p := signedRequestParser()
+	p.Apps = rellenv.Apps{
+		{App: fbapp.New(43, "other-secret", "other"), Label: "Other"},
+		{App: fbapp.New(44, "", ""), Label: ""},
+	}
+	return p
+}
+
+func TestAllApps(t *testing.T) {
+	t.Parallel()
+	var names []string
+	for _, app := range appsParser().AllApps() {
+		names = append(names, app.Name())
+	}
+	ensure.DeepEqual(t, names, []string{"42", "Other", "44"})
+}
+
+func TestSignedRequestPerApp(t *testing.T) {
+	t.Parallel()
+	p := appsParser()
+	sr := signRequest(t, "other-secret", map[string]interface{}{
+		"algorithm": "HMAC-SHA256",
+		"issued_at": time.Now().Unix(),
+	})
+	req := signedRequestForm(t, sr)
+	env, err := p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Website)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+
+	req.Form.Set("appid", "43")
+	ensure.DeepEqual(t, string(p.SignedRequestSecret(req)), "other-secret")
+	env, err = p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Canvas)
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+
+	// unknown apps fall back to the default secret
+	req, err = http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.Form = url.Values{"appid": []string{"99"}}
+	ensure.DeepEqual(t, string(p.SignedRequestSecret(req)), signedRequestSecret)
+}
+
+func TestSignedRequestEmptySecret(t *testing.T) {
+	t.Parallel()
+	p := appsParser()
+	sr := signRequest(t, "", map[string]interface{}{
+		"algorithm": "HMAC-SHA256",
+		"issued_at": time.Now().Unix(),
+		"user_id":   "4",
+	})
+	req := signedRequestForm(t, sr)
+	req.Form.Set("appid", "44")
+	env, err := p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.True(t, env.SignedRequest == nil)
+	ensure.False(t, rellenv.IsEmployee(rellenv.WithEnv(req.Context(), env)))
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+
+	d := p.DiagnoseSignedRequest(req, 44, time.Now())
+	ensure.False(t, d.Valid)
+	ensure.StringContains(t, d.Error, "no secret")
+}
+
+func TestSignedRequestSecretLayers(t *testing.T) {
+	t.Parallel()
+	p := appsParser()
+	p.Presets = rellenv.Presets{"other": {"appid": "43"}}
+	save, err := http.NewRequest("POST", "http://www.fbrell.com/settings/remember?appid=43", nil)
+	ensure.Nil(t, err)
+	cookie, err := p.SettingsCookie(save)
+	ensure.Nil(t, err)
+
+	secret := func(query string, remembered bool) string {
+		req, err := http.NewRequest("GET", "http://www.fbrell.com/?"+query, nil)
+		ensure.Nil(t, err)
+		if remembered {
+			req.AddCookie(cookie)
+		}
+		return string(p.SignedRequestSecret(req))
+	}
+	ensure.DeepEqual(t, secret("", false), signedRequestSecret)
+	ensure.DeepEqual(t, secret("preset=other", false), "other-secret")
+	ensure.DeepEqual(t, secret("", true), "other-secret")
+	ensure.DeepEqual(t, secret("appid=42", true), signedRequestSecret)
+	ensure.DeepEqual(t, secret("appid=43&client_id=42", false), signedRequestSecret)
+	ensure.DeepEqual(t, secret("appid=abc&preset=other", false), "other-secret")
+}
+
+func TestLoadApps(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "apps.json")
+		ensure.Nil(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	apps, err := rellenv.LoadApps(write(
+		`[{"id": 43, "secret": "s", "namespace": "ns", "label": "Other"}]`))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(apps), 1)
+	ensure.DeepEqual(t, apps[0].ID(), uint64(43))
+	ensure.DeepEqual(t, apps[0].Secret(), "s")
+	ensure.DeepEqual(t, apps[0].Namespace(), "ns")
+	ensure.DeepEqual(t, apps[0].Label, "Other")
+
+	_, err = rellenv.LoadApps(write(`[{"id": 43}, {"id": 43}]`))
+	ensure.Err(t, err, regexp.MustCompile("duplicate app 43"))
+	_, err = rellenv.LoadApps(write(`[{"secret": "s"}]`))
+	ensure.Err(t, err, regexp.MustCompile("missing an id"))
+}
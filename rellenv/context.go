@@ -74,12 +74,22 @@ type Env struct {
 	FedCM                bool
 	FedCMAutoPrompt      bool
 	FedCMContext          string
+	FedCMMode            string
+	FedCMLoginHint       string
+	FedCMDomainHint      string
+	FedCMNonce           string
+	localeNegotiated     bool      // locale came from Accept-Language
+	warnings             []Warning // problems with the request, see Warnings
+	preset               string    // name of the Preset applied, if any
+	remembered           bool      // the settings cookie was applied
+	base                 *Env      // the Env before applying the URL, if not the defaults
+	apps                 Apps      // the apps offered in the app picker
 }
 
 // Defaults for the context.
 var defaultContext = &Env{
 	level:                "debug",
-	locale:               "en_US",
+	locale:               DefaultLocale,
 	Status:               true,
 	FrictionlessRequests: true,
 	Host:                 "www.fbrell.com",
@@ -90,15 +100,21 @@ var defaultContext = &Env{
 	FedCM:                false,
 	FedCMAutoPrompt:      false,
 	FedCMContext:          "",
-	Version:              "v25.0",
+	Version:              DefaultVersion.Name,
 }
 
 type EmpChecker interface {
-	Check(uid uint64) bool
+	Check(ctx context.Context, uid uint64) bool
+}
+
+// RequestEmpChecker is implemented by EmpCheckers which check the request
+// itself rather than the signed request user.
+type RequestEmpChecker interface {
+	CheckRequest(r *http.Request) bool
 }
 
 type AppNSFetcher interface {
-	Get(id uint64) string
+	Get(ctx context.Context, id uint64) string
 }
 
 type Parser struct {
@@ -107,6 +123,8 @@ type Parser struct {
 	App                 fbapp.App
 	SignedRequestMaxAge time.Duration
 	Forwarded           *trustforward.Forwarded
+	Presets             Presets
+	Apps                Apps // apps other than App which may be selected
 }
 
 // Create a default context.
@@ -119,88 +137,143 @@ func (p *Parser) Default() *Env {
 
 // Create a context from a HTTP request.
 func (p *Parser) FromRequest(r *http.Request) (*Env, error) {
-	e := p.Default()
-
-	if appid, err := strconv.ParseUint(r.FormValue("appid"), 10, 64); err == nil {
-		e.appID = appid
-	}
-	if appid, err := strconv.ParseUint(r.FormValue("client_id"), 10, 64); err == nil {
-		e.appID = appid
-	}
-	if level := r.FormValue("level"); level != "" {
-		e.level = level
-	}
-	if locale := r.FormValue("locale"); locale != "" {
-		e.locale = locale
-	}
-	if env := r.FormValue("server"); env != "" {
-		e.Env = env
-	}
-	if version := r.FormValue("version"); version != "" {
-		e.Version = version
-	}
-	if viewMode := r.FormValue("view-mode"); viewMode != "" {
-		e.ViewMode = viewMode
-	}
-	if status, err := strconv.ParseBool(r.FormValue("status")); err == nil {
-		e.Status = status
-	}
-	if fr, err := strconv.ParseBool(r.FormValue("frictionlessRequests")); err == nil {
-		e.FrictionlessRequests = fr
-	}
-	if init, err := strconv.ParseBool(r.FormValue("init")); err == nil {
-		e.Init = init
-	}
-	if customLogin, err := strconv.ParseBool(r.FormValue("customLogin")); err == nil {
-		e.CustomLogin = customLogin
-	}
-	if fedcm, err := strconv.ParseBool(r.FormValue("fedcm")); err == nil {
-		e.FedCM = fedcm
-	}
-	if fedcmAutoPrompt, err := strconv.ParseBool(r.FormValue("fedcmAutoPrompt")); err == nil {
-		e.FedCMAutoPrompt = fedcmAutoPrompt
-	}
-	if fedcmContext := r.FormValue("fedcmContext"); fedcmContext != "" {
-		e.FedCMContext = fedcmContext
+	e, localeSet := p.layered(r)
+	checkFedCM(e)
+	if !localeSet {
+		if locale := NegotiateLocale(r.Header.Get("Accept-Language")); locale != "" {
+			e.locale = locale
+			e.localeNegotiated = true
+		}
 	}
 
-	var err error
-	rawSr := r.FormValue("signed_request")
-	if rawSr != "" {
-		e.SignedRequest, err = fbsr.Unmarshal(
-			[]byte(rawSr),
-			p.App.SecretByte(),
-			p.SignedRequestMaxAge,
-		)
-		if err == nil {
-			if e.SignedRequest.Page != nil {
+	if source, raw := signedRequestSource(r, e.appID); raw != "" {
+		sr, err := p.unmarshalSignedRequest(raw, e.appID)
+		switch {
+		case err != nil && source == signedRequestParam:
+			e.warn(source, "", "Ignoring invalid signed request: %s", err)
+		case err == errNoAppSecret:
+			// the JS SDK cookie never verifies without the app secret,
+			// which is expected in development
+		case err != nil:
+			e.warn(source, "", "Ignoring invalid signed request cookie: %s", err)
+		case source == signedRequestParam:
+			e.SignedRequest = sr
+			if sr.Page != nil {
 				e.ViewMode = PageTab
 			} else {
 				e.ViewMode = Canvas
 			}
-		}
-	} else {
-		cookie, _ := r.Cookie(fmt.Sprintf("fbsr_%d", e.appID))
-		if cookie != nil {
-			e.SignedRequest, err = fbsr.Unmarshal(
-				[]byte(cookie.Value),
-				p.App.SecretByte(),
-				p.SignedRequestMaxAge,
-			)
+		default:
+			e.SignedRequest = sr
 		}
 	}
 	e.Host = p.Forwarded.Host(r)
 	e.Scheme = p.Forwarded.Scheme(r)
-	if e.SignedRequest != nil && e.SignedRequest.UserID != 0 {
-		e.isEmployee = p.EmpChecker.Check(e.SignedRequest.UserID)
+	// skip the lookups once the request has been cancelled
+	ctx := r.Context()
+	if rc, ok := p.EmpChecker.(RequestEmpChecker); ok {
+		e.isEmployee = rc.CheckRequest(r)
+	} else if e.SignedRequest != nil && e.SignedRequest.UserID != 0 && ctx.Err() == nil {
+		e.isEmployee = p.EmpChecker.Check(ctx, e.SignedRequest.UserID)
 	}
-	e.appNamespace = p.AppNSFetcher.Get(e.appID)
+	if ctx.Err() == nil {
+		e.appNamespace = p.AppNSFetcher.Get(ctx, e.appID)
+	}
+	e.apps = p.AllApps()
 	if e.Env != "" && !envRegexp.MatchString(e.Env) {
+		e.warn("server", e.Env, "Invalid server %q, using production instead.", e.Env)
 		e.Env = ""
 	}
 	return e, nil
 }
 
+// Returns the Env with the settings in the request applied, and whether a
+// locale was explicitly chosen. Settings are layered: the defaults, then the
+// remembered settings, then the preset and finally the URL. The first three
+// make up the base which Values is relative to.
+func (p *Parser) layered(r *http.Request) (*Env, bool) {
+	e := p.Default()
+	localeSet := r.FormValue("locale") != ""
+	if settings, err := p.readSettingsCookie(r); err != nil {
+		e.warn(SettingsCookieName, "", "%s", err)
+	} else if settings != nil {
+		applyValues(e, settings.Get)
+		e.remembered = true
+		localeSet = localeSet || settings.Get("locale") != ""
+	}
+	if name := r.FormValue("preset"); name != "" {
+		if preset, ok := p.Presets[name]; ok {
+			applyValues(e, preset.Get)
+			e.preset = name
+			localeSet = localeSet || preset.Get("locale") != ""
+		} else {
+			e.warn("preset", name, "Unknown preset %q.", name)
+		}
+	}
+	if e.remembered || e.preset != "" {
+		e.base = e.Copy()
+	}
+	applyValues(e, r.FormValue)
+	return e, localeSet
+}
+
+// Applies the user configurable values, get returns an empty string for
+// missing values which are left untouched.
+func applyValues(e *Env, get func(string) string) {
+	parseBool := func(name string, field *bool) {
+		value := get(name)
+		if value == "" {
+			return
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			e.warn(name, value, "Invalid %s %q, expected true or false.", name, value)
+			return
+		}
+		*field = b
+	}
+	for _, name := range []string{"appid", "client_id"} {
+		if value := get(name); value != "" {
+			appid, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				e.warn(name, value, "Invalid app ID %q, expected a number.", value)
+				continue
+			}
+			e.appID = appid
+		}
+	}
+	if level := get("level"); level != "" {
+		e.level = level
+	}
+	if locale := get("locale"); locale != "" {
+		match, exact := MatchLocale(locale)
+		if !exact {
+			e.warn("locale", locale, "Locale %q is not supported, using %s instead.", locale, match)
+		}
+		e.locale = match
+	}
+	if env := get("server"); env != "" {
+		e.Env = env
+	}
+	if value := get("version"); value != "" {
+		if version := FindVersion(value); version != nil {
+			e.Version = version.Name
+		} else {
+			e.warn("version", value, "Unknown version %q, using %s instead.", value, e.Version)
+		}
+	}
+	if viewMode := get("view-mode"); viewMode != "" {
+		e.ViewMode = viewMode
+	}
+	parseBool("status", &e.Status)
+	parseBool("frictionlessRequests", &e.FrictionlessRequests)
+	parseBool("init", &e.Init)
+	parseBool("customLogin", &e.CustomLogin)
+	parseBool("fedcm", &e.FedCM)
+	parseBool("fedcmAutoPrompt", &e.FedCMAutoPrompt)
+	applyFedCM(e, get)
+}
+
 // Provides a duplicate copy.
 func (c *Env) Copy() *Env {
 	context := *c
@@ -220,7 +293,10 @@ func (c *Env) SdkURL() string {
 func (c *Env) PageTabURL(name string) string {
 	values := url.Values{}
 	values.Set("sk", fmt.Sprintf("app_%d", c.appID))
-	values.Set("app_data", appdata.Encode(c.URL(name)))
+	values.Set("app_data", appdata.Encode(&url.URL{
+		Path:     name,
+		RawQuery: c.externalValues().Encode(),
+	}))
 	url := fburl.URL{
 		Scheme:    c.Scheme,
 		SubDomain: fburl.DWww,
@@ -245,47 +321,88 @@ func (c *Env) CanvasURL(name string) string {
 		SubDomain: fburl.DApps,
 		Env:       c.Env,
 		Path:      name,
-		Values:    c.Values(),
+		Values:    c.externalValues(),
 	}
 	return url.String()
 }
 
-// Serialize the context back to URL values.
+// Serialize the context back to URL values. Values set by a preset are
+// represented by the preset name, and remembered settings are left out.
 func (c *Env) Values() url.Values {
+	if c.base == nil {
+		return c.valuesFrom(c.defaults())
+	}
+	values := c.valuesFrom(c.base)
+	if c.preset != "" {
+		values.Set("preset", c.preset)
+	}
+	return values
+}
+
+// Returns the values for links which leave the site. The settings cookie is
+// not sent when Facebook loads the Canvas or Page Tab iframe cross-site, so
+// the remembered settings are included along with everything else.
+func (c *Env) externalValues() url.Values {
+	if !c.remembered {
+		return c.Values()
+	}
+	return c.valuesFrom(c.defaults())
+}
+
+func (c *Env) defaults() *Env {
+	base := defaultContext.Copy()
+	base.appID = c.defaultAppID
+	return base
+}
+
+// Returns the values which differ from those in base.
+func (c *Env) valuesFrom(base *Env) url.Values {
 	values := url.Values{}
-	if c.appID != c.defaultAppID {
+	if c.appID != base.appID {
 		values.Set("appid", strconv.FormatUint(c.appID, 10))
 	}
-	if c.Env != defaultContext.Env {
+	if c.Env != base.Env {
 		values.Set("server", c.Env)
 	}
-	if c.Version != defaultContext.Version {
+	if c.Version != base.Version {
 		values.Set("version", c.Version)
 	}
-	if c.locale != defaultContext.locale {
+	if c.locale != base.locale && !c.localeNegotiated {
 		values.Set("locale", c.locale)
 	}
-	if c.Init != defaultContext.Init {
+	if c.Init != base.Init {
 		values.Set("init", strconv.FormatBool(c.Init))
 	}
-	if c.Status != defaultContext.Status {
+	if c.Status != base.Status {
 		values.Set("status", strconv.FormatBool(c.Status))
 	}
-	if c.FrictionlessRequests != defaultContext.FrictionlessRequests {
+	if c.FrictionlessRequests != base.FrictionlessRequests {
 		values.Set("frictionlessRequests", strconv.FormatBool(c.FrictionlessRequests))
 	}
-	if c.CustomLogin != defaultContext.CustomLogin {
+	if c.CustomLogin != base.CustomLogin {
 		values.Set("customLogin", strconv.FormatBool(c.CustomLogin))
 	}
-	if c.FedCM != defaultContext.FedCM {
+	if c.FedCM != base.FedCM {
 		values.Set("fedcm", strconv.FormatBool(c.FedCM))
 	}
-	if c.FedCMAutoPrompt != defaultContext.FedCMAutoPrompt {
+	if c.FedCMAutoPrompt != base.FedCMAutoPrompt {
 		values.Set("fedcmAutoPrompt", strconv.FormatBool(c.FedCMAutoPrompt))
 	}
-	if c.FedCMContext != defaultContext.FedCMContext {
+	if c.FedCMContext != base.FedCMContext {
 		values.Set("fedcmContext", c.FedCMContext)
 	}
+	if c.FedCMMode != base.FedCMMode {
+		values.Set("fedcmMode", c.FedCMMode)
+	}
+	if c.FedCMLoginHint != base.FedCMLoginHint {
+		values.Set("fedcmLoginHint", c.FedCMLoginHint)
+	}
+	if c.FedCMDomainHint != base.FedCMDomainHint {
+		values.Set("fedcmDomainHint", c.FedCMDomainHint)
+	}
+	if c.FedCMNonce != base.FedCMNonce {
+		values.Set("fedcmNonce", c.FedCMNonce)
+	}
 	return values
 }
 
@@ -332,7 +449,14 @@ func (c *Env) MarshalJSON() ([]byte, error) {
 		FedCM                bool                  `json:"fedcm"`
 		FedCMAutoPrompt      bool                  `json:"fedcmAutoPrompt"`
 		FedCMContext          string                `json:"fedcmContext,omitempty"`
+		FedCMMode            string                `json:"fedcmMode,omitempty"`
+		FedCMLoginHint       string                `json:"fedcmLoginHint,omitempty"`
+		FedCMDomainHint      string                `json:"fedcmDomainHint,omitempty"`
+		FedCMNonce           string                `json:"fedcmNonce,omitempty"`
 		IsEmployee           bool                  `json:"isEmployee,omitempty"`
+		Preset               string                `json:"preset,omitempty"`
+		Remembered           bool                  `json:"remembered,omitempty"`
+		Warnings             []Warning             `json:"warnings,omitempty"`
 	}
 	return json.Marshal(envJSON{
 		AppID:                strconv.FormatUint(c.appID, 10),
@@ -347,7 +471,14 @@ func (c *Env) MarshalJSON() ([]byte, error) {
 		FedCM:                c.FedCM,
 		FedCMAutoPrompt:      c.FedCMAutoPrompt,
 		FedCMContext:          c.FedCMContext,
+		FedCMMode:            c.FedCMMode,
+		FedCMLoginHint:       c.FedCMLoginHint,
+		FedCMDomainHint:      c.FedCMDomainHint,
+		FedCMNonce:           c.FedCMNonce,
 		IsEmployee:           c.isEmployee,
+		Preset:               c.preset,
+		Remembered:           c.remembered,
+		Warnings:             c.warnings,
 	})
 }
 
@@ -371,6 +502,16 @@ func WithEnv(ctx context.Context, env *Env) context.Context {
 	return context.WithValue(ctx, contextEnvKey, env)
 }
 
+// Preset returns the name of the preset applied, if any.
+func (c *Env) Preset() string {
+	return c.preset
+}
+
+// Remembered returns true if settings from the settings cookie were applied.
+func (c *Env) Remembered() bool {
+	return c.remembered
+}
+
 // Level returns the log level.
 func (c *Env) Level() string {
 	return c.level
@@ -381,6 +522,17 @@ func (c *Env) Locale() string {
 	return c.locale
 }
 
+// Warnings returns problems found with the request, like unsupported values
+// which were replaced.
+func (c *Env) Warnings() []Warning {
+	return c.warnings
+}
+
+// Apps returns the apps which may be selected, the default app first.
+func (c *Env) Apps() Apps {
+	return c.apps
+}
+
 // IsEmployee returns true if the Context is known to be that of an employee.
 func IsEmployee(ctx context.Context) bool {
 	if env, err := FromContext(ctx); err == nil {
@@ -22,15 +22,21 @@ package rellenv_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/daaku/go.signedrequest/appdata"
 	"github.com/daaku/go.trustforward"
 	"github.com/facebookgo/ensure"
 	"github.com/facebookgo/fbapp"
 	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
 )
 
 const (
@@ -40,13 +46,13 @@ const (
 
 type funcEmpChecker func(uint64) bool
 
-func (f funcEmpChecker) Check(uid uint64) bool {
+func (f funcEmpChecker) Check(ctx context.Context, uid uint64) bool {
 	return f(uid)
 }
 
 type funcAppNSFetcher func(uint64) string
 
-func (f funcAppNSFetcher) Get(id uint64) string {
+func (f funcAppNSFetcher) Get(ctx context.Context, id uint64) string {
 	return f(id)
 }
 
@@ -113,6 +119,33 @@ func TestComplex(t *testing.T) {
 	ensure.StringContains(t, env.SdkURL(), "en_PI")
 }
 
+func TestCancelledRequestSkipsLookups(t *testing.T) {
+	t.Parallel()
+	parser := defaultParser()
+	parser.AppNSFetcher = funcAppNSFetcher(func(uint64) string {
+		t.Fatal("unexpected namespace lookup")
+		return ""
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	_, err = parser.FromRequest(req)
+	ensure.Nil(t, err)
+}
+
+func TestRequestEmpChecker(t *testing.T) {
+	t.Parallel()
+	parser := defaultParser()
+	parser.EmpChecker = &empcheck.Header{Name: "X-Forwarded-Email"}
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.Header.Set("X-Forwarded-Email", "jane@example.com")
+	env, err := parser.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.True(t, rellenv.IsEmployee(rellenv.WithEnv(context.Background(), env)))
+}
+
 func TestPageTabURLBeta(t *testing.T) {
 	t.Parallel()
 	env, _ := fromValues(t, url.Values{"server": []string{"beta"}})
@@ -146,3 +179,338 @@ func TestCanvasURL(t *testing.T) {
 	ensure.StringContains(t, canvasURL,
 		fmt.Sprintf("https://apps.facebook.com/%s/", defaultAppNS))
 }
+
+func TestVersionNormalized(t *testing.T) {
+	t.Parallel()
+	for _, raw := range []string{"v21.0", "21", "V21", "21.0"} {
+		env, _ := fromValues(t, url.Values{"version": []string{raw}})
+		ensure.DeepEqual(t, env.Version, "v21.0", raw)
+		ensure.DeepEqual(t, env.Values().Get("version"), "v21.0", raw)
+	}
+}
+
+func TestVersionUnknown(t *testing.T) {
+	t.Parallel()
+	for _, raw := range []string{"v2.50", "v99.0", "latest", "v21.0.1"} {
+		env, _ := fromValues(t, url.Values{"version": []string{raw}})
+		ensure.DeepEqual(t, env.Version, rellenv.DefaultVersion.Name, raw)
+		ensure.DeepEqual(t, env.Values().Get("version"), "", raw)
+	}
+}
+
+func TestVersionDeprecated(t *testing.T) {
+	t.Parallel()
+	v := rellenv.FindVersion("v18.0")
+	ensure.False(t, v.IsDeprecated(v.Deprecated.Add(-time.Second)))
+	ensure.True(t, v.IsDeprecated(v.Deprecated))
+	ensure.False(t, rellenv.DefaultVersion.IsDeprecated(time.Now()))
+}
+
+func TestLocaleMatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		raw, locale string
+		warn        bool
+	}{
+		{"pt_BR", "pt_BR", false},
+		{"pt-br", "pt_BR", false},
+		{"fr_XX", "fr_FR", true},
+		{"es", "es_ES", true},
+		{"xx_YY", rellenv.DefaultLocale, true},
+		{"../../evil", rellenv.DefaultLocale, true},
+	}
+	for _, c := range cases {
+		env, _ := fromValues(t, url.Values{"locale": []string{c.raw}})
+		ensure.DeepEqual(t, env.Locale(), c.locale, c.raw)
+		ensure.DeepEqual(t, len(env.Warnings()) > 0, c.warn, c.raw)
+	}
+}
+
+func TestLocaleAcceptLanguage(t *testing.T) {
+	t.Parallel()
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.Header.Set("Accept-Language", "xx;q=0.9, de-CH, en;q=0.5")
+	env, err := defaultParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.Locale(), "de_DE")
+	ensure.DeepEqual(t, env.Values().Get("locale"), "")
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+
+	req.Form = url.Values{"locale": []string{"ja_JP"}}
+	env, err = defaultParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.Locale(), "ja_JP")
+}
+
+func presetParser() *rellenv.Parser {
+	p := defaultParser()
+	p.Presets = rellenv.Presets{
+		"fedcm-beta": {"server": "beta", "fedcm": "true", "version": "v24.0"},
+	}
+	return p
+}
+
+// Returns a parser with an app secret to sign the settings cookie.
+func settingsParser() *rellenv.Parser {
+	p := presetParser()
+	p.App = fbapp.New(defaultFacebookAppID, "secret", "")
+	return p
+}
+
+func TestPreset(t *testing.T) {
+	t.Parallel()
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/?preset=fedcm-beta&status=0", nil)
+	ensure.Nil(t, err)
+	env, err := presetParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.Subset(t, env, &rellenv.Env{
+		Env:     "beta",
+		FedCM:   true,
+		Version: "v24.0",
+		Status:  false,
+	})
+	ensure.DeepEqual(t, env.Preset(), "fedcm-beta")
+	ensure.DeepEqual(t, env.Values(), url.Values{
+		"preset": []string{"fedcm-beta"},
+		"status": []string{"false"},
+	})
+}
+
+func TestPresetOverride(t *testing.T) {
+	t.Parallel()
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/?preset=fedcm-beta&server=latest&fedcm=false", nil)
+	ensure.Nil(t, err)
+	env, err := presetParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.Env, "latest")
+	ensure.False(t, env.FedCM)
+	ensure.DeepEqual(t, env.Values(), url.Values{
+		"preset": []string{"fedcm-beta"},
+		"server": []string{"latest"},
+		"fedcm":  []string{"false"},
+	})
+}
+
+func TestPresetUnknown(t *testing.T) {
+	t.Parallel()
+	env, _ := fromValues(t, url.Values{"preset": []string{"nope"}})
+	ensure.DeepEqual(t, env.Preset(), "")
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+	ensure.DeepEqual(t, env.Values(), url.Values{})
+}
+
+func TestPresetsInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]rellenv.Presets{
+		"unknown parameter":  {"a": {"signed_request": "x"}},
+		"unknown version":    {"a": {"version": "v2.50"}},
+		"unsupported locale": {"a": {"locale": "xx_YY"}},
+		"FedCM mode":         {"a": {"fedcmMode": "loud"}},
+	}
+	for msg, presets := range cases {
+		ensure.Err(t, presets.Validate(), regexp.MustCompile(msg))
+	}
+}
+
+func TestSettingsCookie(t *testing.T) {
+	t.Parallel()
+	p := settingsParser()
+	save, err := http.NewRequest("POST", "http://www.fbrell.com/settings/remember?server=beta&appid=123&status=true", nil)
+	ensure.Nil(t, err)
+	cookie, err := p.SettingsCookie(save)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cookie.Name, rellenv.SettingsCookieName)
+
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/?fedcm=true", nil)
+	ensure.Nil(t, err)
+	req.AddCookie(cookie)
+	env, err := p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.True(t, env.Remembered())
+	ensure.Subset(t, env, &rellenv.Env{Env: "beta", FedCM: true, Status: true})
+	ensure.DeepEqual(t, env.Values(), url.Values{"fedcm": []string{"true"}})
+
+	// the URL overrides remembered settings
+	req.Form = url.Values{"server": []string{"latest"}}
+	env, err = p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.Env, "latest")
+	ensure.DeepEqual(t, env.Values(), url.Values{"server": []string{"latest"}})
+}
+
+func TestSettingsCookieExternalURLs(t *testing.T) {
+	t.Parallel()
+	p := settingsParser()
+	save, err := http.NewRequest("POST", "http://www.fbrell.com/settings/remember?server=beta&appid=123", nil)
+	ensure.Nil(t, err)
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/?fedcm=true", nil)
+	ensure.Nil(t, err)
+	cookie, err := p.SettingsCookie(save)
+	ensure.Nil(t, err)
+	req.AddCookie(cookie)
+	env, err := p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.Values(), url.Values{"fedcm": []string{"true"}})
+
+	// the cookie is not sent inside the Facebook iframes
+	canvas, err := url.Parse(env.CanvasURL("/"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, canvas.Query(), url.Values{
+		"appid":  []string{"123"},
+		"server": []string{"beta"},
+		"fedcm":  []string{"true"},
+	})
+	pageTab, err := url.Parse(env.PageTabURL("/"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, pageTab.Query().Get("sk"), "app_123")
+	appData, err := appdata.Decode(pageTab.Query().Get("app_data"))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, appData.Query().Get("appid"), "123")
+}
+
+func TestSettingsCookieTampered(t *testing.T) {
+	t.Parallel()
+	p := settingsParser()
+	save, err := http.NewRequest("POST", "http://www.fbrell.com/settings/remember?server=beta", nil)
+	ensure.Nil(t, err)
+	cookie, err := p.SettingsCookie(save)
+	ensure.Nil(t, err)
+	cookie.Value = "c2VydmVyPWV2aWw" + cookie.Value[strings.Index(cookie.Value, "."):]
+
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.AddCookie(cookie)
+	env, err := p.FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.False(t, env.Remembered())
+	ensure.DeepEqual(t, env.Env, "")
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+}
+
+func TestSettingsCookieNoSecret(t *testing.T) {
+	t.Parallel()
+	save, err := http.NewRequest("POST", "http://www.fbrell.com/settings/remember?server=beta", nil)
+	ensure.Nil(t, err)
+	_, err = presetParser().SettingsCookie(save)
+	ensure.DeepEqual(t, err, rellenv.ErrNoSettingsSecret)
+
+	// without a secret to verify it, a cookie is not accepted either
+	cookie, err := settingsParser().SettingsCookie(save)
+	ensure.Nil(t, err)
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.AddCookie(cookie)
+	env, err := presetParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.False(t, env.Remembered())
+	ensure.DeepEqual(t, env.Env, "")
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+}
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		param, value string
+	}{
+		{"appid", "abc"},
+		{"client_id", "-1"},
+		{"status", "maybe"},
+		{"fedcm", "yes please"},
+		{"server", "bad!"},
+		{"version", "v2.50"},
+	}
+	for _, c := range cases {
+		env, _ := fromValues(t, url.Values{c.param: []string{c.value}})
+		warnings := env.Warnings()
+		ensure.DeepEqual(t, len(warnings), 1, c.param)
+		ensure.Subset(t, warnings[0], rellenv.Warning{Param: c.param, Value: c.value})
+	}
+}
+
+func TestWarningsSignedRequest(t *testing.T) {
+	t.Parallel()
+	env, _ := fromValues(t, url.Values{"signed_request": []string{"not.valid"}})
+	ensure.True(t, env.SignedRequest == nil)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Website)
+	warnings := env.Warnings()
+	ensure.DeepEqual(t, len(warnings), 1)
+	ensure.DeepEqual(t, warnings[0].Param, "signed_request")
+	ensure.DeepEqual(t, warnings[0].Value, "")
+
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.AddCookie(&http.Cookie{Name: fmt.Sprintf("fbsr_%d", defaultFacebookAppID), Value: "x.y"})
+	env, err = signedRequestParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+}
+
+func TestWarningsJSON(t *testing.T) {
+	t.Parallel()
+	env, _ := fromValues(t, url.Values{"status": []string{"maybe"}})
+	b, err := json.Marshal(env)
+	ensure.Nil(t, err)
+	ensure.StringContains(t, string(b), `"warnings":[{"param":"status","value":"maybe"`)
+}
+
+func TestFedCMOptions(t *testing.T) {
+	t.Parallel()
+	values := url.Values{
+		"fedcm":           []string{"true"},
+		"fedcmContext":    []string{"continue"},
+		"fedcmMode":       []string{"active"},
+		"fedcmLoginHint":  []string{"user@example.com"},
+		"fedcmDomainHint": []string{"example.com"},
+		"fedcmNonce":      []string{"n-0S6_WzA2Mj"},
+	}
+	env, _ := fromValues(t, values)
+	ensure.Subset(t, env, &rellenv.Env{
+		FedCM:           true,
+		FedCMContext:    "continue",
+		FedCMMode:       rellenv.FedCMActive,
+		FedCMLoginHint:  "user@example.com",
+		FedCMDomainHint: "example.com",
+		FedCMNonce:      "n-0S6_WzA2Mj",
+	})
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+	ensure.DeepEqual(t, env.Values(), values)
+}
+
+func TestFedCMInvalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"fedcmContext":    "login",
+		"fedcmMode":       "button",
+		"fedcmLoginHint":  "a\nb",
+		"fedcmDomainHint": "example.com/path",
+		"fedcmNonce":      "has space",
+	}
+	for param, value := range cases {
+		env, _ := fromValues(t, url.Values{
+			"fedcm": []string{"true"},
+			param:   []string{value},
+		})
+		ensure.DeepEqual(t, env.Values(), url.Values{"fedcm": []string{"true"}}, param)
+		warnings := env.Warnings()
+		ensure.DeepEqual(t, len(warnings), 1, param)
+		ensure.Subset(t, warnings[0], rellenv.Warning{Param: param, Value: value})
+	}
+}
+
+func TestFedCMConsistency(t *testing.T) {
+	t.Parallel()
+	env, _ := fromValues(t, url.Values{"fedcmMode": []string{"active"}})
+	ensure.DeepEqual(t, env.FedCMMode, rellenv.FedCMActive)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+	ensure.DeepEqual(t, env.Warnings()[0].Param, "fedcm")
+
+	env, _ = fromValues(t, url.Values{
+		"fedcm":           []string{"true"},
+		"fedcmMode":       []string{"active"},
+		"fedcmAutoPrompt": []string{"true"},
+	})
+	ensure.False(t, env.FedCMAutoPrompt)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+	ensure.DeepEqual(t, env.Warnings()[0].Param, "fedcmAutoPrompt")
+}
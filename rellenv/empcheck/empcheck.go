@@ -18,54 +18,148 @@
  * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
  */
 
-// Package empcheck checks for employees.
+// Package empcheck checks for employees. The Checker asks the Graph API,
+// while UserIDs and Header suit self-hosted deployments without a
+// privileged application.
 package empcheck
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/facebookgo/fbapi"
 	"github.com/facebookgo/fbapp"
-	"github.com/golang/groupcache/lru"
+	"github.com/fbsamples/fbrell/cache"
+	"golang.org/x/sync/singleflight"
 )
 
-var fields = fbapi.ParamFields("is_employee")
+const (
+	// DefaultTTL is how long a result is cached when TTL is not set.
+	DefaultTTL = time.Hour
 
-type cacheKey uint64
+	// DefaultErrorTTL is how long a failed lookup is cached when ErrorTTL is
+	// not set.
+	DefaultErrorTTL = time.Minute
+
+	// DefaultTimeout bounds a Graph API lookup when Timeout is not set.
+	DefaultTimeout = 5 * time.Second
+)
+
+var (
+	employeeFields = fbapi.ParamFields("is_employee")
+	emailFields    = fbapi.ParamFields("email")
+)
 
 type user struct {
-	IsEmployee bool `json:"is_employee"`
+	IsEmployee bool   `json:"is_employee"`
+	Email      string `json:"email"`
 }
 
 type Logger interface {
 	Printf(format string, v ...interface{})
 }
 
+// Stats are the cache counters for a Checker.
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
 type Checker struct {
 	FbApiClient *fbapi.Client
 	App         fbapp.App
 	Logger      Logger
-	Cache       *lru.Cache
-	mu          sync.Mutex
+	Cache       *cache.Cache[uint64, bool]
+	Domains     []string      // if set, the email field is checked instead of is_employee
+	TTL         time.Duration // defaults to DefaultTTL
+	ErrorTTL    time.Duration // defaults to DefaultErrorTTL
+	Timeout     time.Duration // defaults to DefaultTimeout
+	group       singleflight.Group
+	errors      atomic.Int64
 }
 
 // Check if the user is a Facebook Employee. This only available by
-// special permission granted to an application by Facebook.
-func (c *Checker) Check(id uint64) bool {
-	c.mu.Lock()
-	is, ok := c.Cache.Get(cacheKey(id))
-	c.mu.Unlock()
-	if ok {
-		return is.(bool)
+// special permission granted to an application by Facebook. If Domains is
+// set, check if the user's email is in one of them instead.
+func (c *Checker) Check(ctx context.Context, id uint64) bool {
+	if is, ok := c.Cache.Get(id); ok {
+		return is
+	}
+	return c.Refresh(ctx, id)
+}
+
+// Refresh looks up the user bypassing the cache, and caches the result.
+// Concurrent lookups for the same user share one Graph API call. If ctx is
+// done first, false is returned while the lookup continues for the others.
+func (c *Checker) Refresh(ctx context.Context, id uint64) bool {
+	key := strconv.FormatUint(id, 10)
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		return c.fetch(id), nil
+	})
+	select {
+	case res := <-ch:
+		return res.Val.(bool)
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Looks up the user and caches the result. The lookup is not bound to any
+// one request, since its result may be shared.
+func (c *Checker) fetch(id uint64) bool {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	is, err := c.lookup(ctx, id)
+	ttl := c.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
 	}
+	if err != nil {
+		c.errors.Add(1)
+		c.Logger.Printf("Ignoring error in IsEmployee: %s", err)
+		ttl = c.ErrorTTL
+		if ttl == 0 {
+			ttl = DefaultErrorTTL
+		}
+	}
+
+	c.Cache.AddTTL(id, is, ttl)
+	return is
+}
+
+// Invalidate drops the cached result for the user.
+func (c *Checker) Invalidate(id uint64) {
+	c.Cache.Remove(id)
+}
 
+// Stats returns the current counters.
+func (c *Checker) Stats() Stats {
+	s := c.Cache.Stats()
+	return Stats{
+		Hits:   s.Hits,
+		Misses: s.Misses,
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *Checker) lookup(ctx context.Context, id uint64) (bool, error) {
+	fields := employeeFields
+	if len(c.Domains) > 0 {
+		fields = emailFields
+	}
 	values, err := fbapi.ParamValues(c.App, fields)
 	if err != nil {
-		c.Logger.Printf("Ignoring error in IsEmployee ParamValues: %s", err)
-		return false
+		return false, err
 	}
 
 	var user user
@@ -74,19 +168,17 @@ func (c *Checker) Check(id uint64) bool {
 		RawQuery: values.Encode(),
 	}
 	req := http.Request{Method: "GET", URL: &u}
-	_, err = c.FbApiClient.Do(&req, &user)
+	_, err = c.FbApiClient.Do(req.WithContext(ctx), &user)
 	if err != nil {
 		if apiErr, ok := err.(*fbapi.Error); ok {
 			if apiErr.Code == 100 { // common error with test users
-				return false
+				return false, nil
 			}
 		}
-		c.Logger.Printf("Ignoring error in IsEmployee FbApiClient.Do: %s", err)
-		return false
+		return false, err
 	}
-
-	c.mu.Lock()
-	c.Cache.Add(cacheKey(id), user.IsEmployee)
-	c.mu.Unlock()
-	return user.IsEmployee
+	if len(c.Domains) > 0 {
+		return inDomains(user.Email, c.Domains), nil
+	}
+	return user.IsEmployee, nil
 }
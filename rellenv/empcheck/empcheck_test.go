@@ -0,0 +1,183 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package empcheck_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapi"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/cache"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Printf(format string, v ...interface{}) {}
+
+var ctx = context.Background()
+
+// Responds with the current body and counts the requests. If block is set,
+// responses wait for it to be closed or the request to be cancelled.
+type graph struct {
+	status   int
+	body     string
+	block    chan struct{}
+	mu       sync.Mutex
+	requests int
+	query    string
+}
+
+func (g *graph) RoundTrip(r *http.Request) (*http.Response, error) {
+	g.mu.Lock()
+	g.requests++
+	g.query = r.URL.RawQuery
+	g.mu.Unlock()
+	if g.block != nil {
+		select {
+		case <-g.block:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+	return &http.Response{
+		StatusCode: g.status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(g.body)),
+		Request:    r,
+	}, nil
+}
+
+func newChecker(g *graph) (*empcheck.Checker, *clock.Mock) {
+	c := clock.NewMock()
+	return &empcheck.Checker{
+		FbApiClient: &fbapi.Client{Transport: g},
+		App:         fbapp.New(1, "secret", ""),
+		Logger:      discardLogger{},
+		Cache:       &cache.Cache[uint64, bool]{Size: 10, Clock: c},
+		TTL:         time.Hour,
+		ErrorTTL:    time.Minute,
+	}, c
+}
+
+func TestCheckTTL(t *testing.T) {
+	g := &graph{status: 200, body: `{"is_employee":true}`}
+	checker, c := newChecker(g)
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 1)
+
+	g.body = `{"is_employee":false}`
+	c.Add(time.Hour)
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 2)
+	ensure.DeepEqual(t, checker.Stats(), empcheck.Stats{Hits: 1, Misses: 2})
+}
+
+func TestCheckErrorCached(t *testing.T) {
+	g := &graph{status: 500, body: `{"error":{"message":"boom","code":1}}`}
+	checker, c := newChecker(g)
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 1)
+
+	g.status, g.body = 200, `{"is_employee":true}`
+	c.Add(time.Minute)
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 2)
+	ensure.DeepEqual(t, checker.Stats(), empcheck.Stats{Hits: 1, Misses: 2, Errors: 1})
+}
+
+func TestCheckTestUser(t *testing.T) {
+	g := &graph{status: 400, body: `{"error":{"message":"test user","code":100}}`}
+	checker, c := newChecker(g)
+	ensure.False(t, checker.Check(ctx, 42))
+	c.Add(time.Minute)
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 1)
+	ensure.DeepEqual(t, checker.Stats().Errors, int64(0))
+}
+
+func TestInvalidateAndRefresh(t *testing.T) {
+	g := &graph{status: 200, body: `{"is_employee":true}`}
+	checker, _ := newChecker(g)
+	ensure.True(t, checker.Check(ctx, 42))
+
+	g.body = `{"is_employee":false}`
+	ensure.False(t, checker.Refresh(ctx, 42))
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 2)
+
+	g.body = `{"is_employee":true}`
+	checker.Invalidate(42)
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 3)
+}
+
+func TestCheckCoalesced(t *testing.T) {
+	g := &graph{status: 200, body: `{"is_employee":true}`, block: make(chan struct{})}
+	checker, _ := newChecker(g)
+	var wg sync.WaitGroup
+	results := make([]bool, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = checker.Check(ctx, 42)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(g.block)
+	wg.Wait()
+	for _, is := range results {
+		ensure.True(t, is)
+	}
+	ensure.DeepEqual(t, g.requests, 1)
+}
+
+func TestCheckCancelled(t *testing.T) {
+	g := &graph{status: 200, body: `{"is_employee":true}`, block: make(chan struct{})}
+	checker, _ := newChecker(g)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	ensure.False(t, checker.Check(cctx, 42))
+
+	// the lookup continues for other requests
+	close(g.block)
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, g.requests, 1)
+}
+
+func TestCheckTimeout(t *testing.T) {
+	g := &graph{status: 200, body: `{"is_employee":true}`, block: make(chan struct{})}
+	checker, _ := newChecker(g)
+	checker.Timeout = 10 * time.Millisecond
+	ensure.False(t, checker.Check(ctx, 42))
+	ensure.DeepEqual(t, checker.Stats().Errors, int64(1))
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package empcheck
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// UserIDs is a static allowlist of employee user IDs.
+type UserIDs []uint64
+
+// Check if the user is in the allowlist.
+func (ids UserIDs) Check(ctx context.Context, id uint64) bool {
+	for _, allowed := range ids {
+		if id != 0 && id == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Header trusts a header set by an authenticating proxy, such as the
+// X-Forwarded-Email header set by oauth2-proxy. The proxy must strip the
+// header from incoming requests, otherwise anyone can set it.
+type Header struct {
+	Name    string
+	Domains []string // if set, the header must be an email in one of them
+}
+
+// Check always returns false, since the header is checked by CheckRequest.
+func (h *Header) Check(ctx context.Context, id uint64) bool {
+	return false
+}
+
+// CheckRequest returns true if the request has the header, and it is in one
+// of the Domains if they are set.
+func (h *Header) CheckRequest(r *http.Request) bool {
+	value := strings.TrimSpace(r.Header.Get(h.Name))
+	if value == "" {
+		return false
+	}
+	if len(h.Domains) == 0 {
+		return true
+	}
+	return inDomains(value, h.Domains)
+}
+
+// Returns true if the email address is in one of the domains.
+func inDomains(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, d := range domains {
+		if strings.EqualFold(domain, strings.TrimPrefix(d, "@")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package empcheck_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"github.com/fbsamples/fbrell/rellenv/empcheck"
+)
+
+func TestUserIDs(t *testing.T) {
+	ids := empcheck.UserIDs{4, 1234}
+	ensure.True(t, ids.Check(ctx, 4))
+	ensure.False(t, ids.Check(ctx, 5))
+	ensure.False(t, ids.Check(ctx, 0))
+}
+
+func TestHeader(t *testing.T) {
+	cases := []struct {
+		Domains []string
+		Value   string
+		Is      bool
+	}{
+		{nil, "", false},
+		{nil, "anyone", true},
+		{[]string{"example.com"}, "jane@example.com", true},
+		{[]string{"@Example.com"}, "jane@EXAMPLE.COM", true},
+		{[]string{"example.com"}, "jane@example.com.evil", false},
+		{[]string{"example.com"}, "jane@sub.example.com", false},
+		{[]string{"example.com"}, "example.com", false},
+	}
+	for _, c := range cases {
+		h := &empcheck.Header{Name: "X-Forwarded-Email", Domains: c.Domains}
+		r := &http.Request{Header: http.Header{}}
+		if c.Value != "" {
+			r.Header.Set("X-Forwarded-Email", c.Value)
+		}
+		ensure.DeepEqual(t, h.CheckRequest(r), c.Is, c.Value)
+		ensure.False(t, h.Check(ctx, 4))
+	}
+}
+
+func TestCheckEmailDomains(t *testing.T) {
+	g := &graph{status: 200, body: `{"email":"jane@example.com","id":"42"}`}
+	checker, _ := newChecker(g)
+	checker.Domains = []string{"example.com"}
+	ensure.True(t, checker.Check(ctx, 42))
+	ensure.StringContains(t, g.query, "fields=email")
+
+	g.body = `{"email":"jane@other.com","id":"43"}`
+	ensure.False(t, checker.Check(ctx, 43))
+	ensure.DeepEqual(t, g.requests, 2)
+}
@@ -0,0 +1,140 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// FedCMContexts are the valid values for fedcmContext, which selects the
+// wording of the browser sign in prompt.
+var FedCMContexts = []string{"signin", "signup", "use", "continue"}
+
+// FedCM modes, passive is the browser default.
+const (
+	FedCMPassive = "passive"
+	FedCMActive  = "active"
+)
+
+// FedCMModes are the valid values for fedcmMode.
+var FedCMModes = []string{FedCMPassive, FedCMActive}
+
+// Longest accepted login hint, domain hint or nonce.
+const maxFedCMValueLength = 256
+
+var (
+	fedcmDomainHintRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*$`)
+	fedcmNonceRegexp      = regexp.MustCompile(`^[\x21-\x7e]+$`)
+)
+
+// The string FedCM parameters along with their validation.
+var fedcmParams = map[string]func(string) error{
+	"fedcmContext": func(value string) error {
+		return oneOf("FedCM context", value, FedCMContexts)
+	},
+	"fedcmMode": func(value string) error {
+		return oneOf("FedCM mode", value, FedCMModes)
+	},
+	"fedcmLoginHint": func(value string) error {
+		return validText("FedCM login hint", value)
+	},
+	"fedcmDomainHint": func(value string) error {
+		if err := validText("FedCM domain hint", value); err != nil {
+			return err
+		}
+		if value != "any" && !fedcmDomainHintRegexp.MatchString(value) {
+			return fmt.Errorf("Invalid FedCM domain hint %q, expected a domain or any.", value)
+		}
+		return nil
+	},
+	"fedcmNonce": func(value string) error {
+		if err := validText("FedCM nonce", value); err != nil {
+			return err
+		}
+		if !fedcmNonceRegexp.MatchString(value) {
+			return fmt.Errorf("Invalid FedCM nonce %q, expected printable ASCII without spaces.", value)
+		}
+		return nil
+	},
+}
+
+func oneOf(name, value string, valid []string) error {
+	for _, v := range valid {
+		if value == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid %s %q, expected one of %s.", name, value, strings.Join(valid, ", "))
+}
+
+func validText(name, value string) error {
+	if len(value) > maxFedCMValueLength {
+		return fmt.Errorf("Invalid %s, longer than %d characters.", name, maxFedCMValueLength)
+	}
+	if strings.IndexFunc(value, unicode.IsControl) != -1 {
+		return fmt.Errorf("Invalid %s %q, contains control characters.", name, value)
+	}
+	return nil
+}
+
+// Applies the FedCM parameters, skipping invalid values with a warning.
+func applyFedCM(e *Env, get func(string) string) {
+	fields := []struct {
+		name  string
+		field *string
+	}{
+		{"fedcmContext", &e.FedCMContext},
+		{"fedcmMode", &e.FedCMMode},
+		{"fedcmLoginHint", &e.FedCMLoginHint},
+		{"fedcmDomainHint", &e.FedCMDomainHint},
+		{"fedcmNonce", &e.FedCMNonce},
+	}
+	for _, f := range fields {
+		value := get(f.name)
+		if value == "" {
+			continue
+		}
+		if err := fedcmParams[f.name](value); err != nil {
+			e.warn(f.name, value, "%s", err)
+			continue
+		}
+		*f.field = value
+	}
+}
+
+// Checks the FedCM options are consistent once all settings are applied.
+func checkFedCM(e *Env) {
+	if !e.FedCM {
+		if e.FedCMAutoPrompt || e.FedCMContext != "" || e.FedCMMode != "" ||
+			e.FedCMLoginHint != "" || e.FedCMDomainHint != "" || e.FedCMNonce != "" {
+			e.warn("fedcm", "", "FedCM options are ignored unless fedcm is enabled.")
+		}
+		return
+	}
+	if e.FedCMMode == FedCMActive && e.FedCMAutoPrompt {
+		e.warn("fedcmAutoPrompt", "true",
+			"FedCM auto prompt is not supported in active mode, disabling it.")
+		e.FedCMAutoPrompt = false
+	}
+}
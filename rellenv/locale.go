@@ -0,0 +1,144 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when no supported locale is requested.
+const DefaultLocale = "en_US"
+
+// Locales are the locales supported by the JS SDK, with DefaultLocale first.
+var Locales = []string{
+	"en_US", "af_ZA", "ak_GH", "am_ET", "ar_AR", "as_IN", "ay_BO", "az_AZ",
+	"be_BY", "bg_BG", "bm_ML", "bn_IN", "bp_IN", "br_FR", "bs_BA", "bv_DE",
+	"ca_ES", "cb_IQ", "ck_US", "co_FR", "cs_CZ", "cx_PH", "cy_GB",
+	"da_DK", "de_DE",
+	"eh_IN", "el_GR", "em_ZM", "en_GB", "en_IN", "en_OP", "en_PI", "en_UD", "en_XA", "eo_EO",
+	"es_CL", "es_CO", "es_ES", "es_LA", "es_MX", "es_VE", "et_EE", "eu_ES",
+	"fa_IR", "fb_AA", "fb_AC", "fb_AR", "fb_HA", "fb_HX", "fb_LL", "fb_LS", "fb_LT", "fb_RL", "fb_ZH", "fbt_AC",
+	"ff_NG", "fi_FI", "fn_IT", "fo_FO", "fr_CA", "fr_FR", "fv_NG", "fy_NL",
+	"ga_IE", "gl_ES", "gn_PY", "gu_IN", "gx_GR",
+	"ha_NG", "he_IL", "hi_FB", "hi_IN", "hr_HR", "ht_HT", "hu_HU", "hy_AM",
+	"id_ID", "ig_NG", "ik_US", "is_IS", "it_IT", "iu_CA",
+	"ja_JP", "ja_KS", "jv_ID",
+	"ka_GE", "kk_KZ", "km_KH", "kn_IN", "ko_KR", "ks_IN", "ku_TR", "ky_KG",
+	"la_VA", "lg_UG", "li_NL", "ln_CD", "lo_LA", "lr_IT", "lt_LT", "lv_LV",
+	"mg_MG", "mi_NZ", "mk_MK", "ml_IN", "mn_MN", "mos_BF", "mr_IN", "ms_MY", "mt_MT", "my_MM",
+	"nb_NO", "nd_ZW", "ne_NP", "nh_MX", "nl_BE", "nl_NL", "nn_NO", "nr_ZA", "ns_ZA", "ny_MW",
+	"om_ET", "or_IN",
+	"pa_IN", "pcm_NG", "pl_PL", "ps_AF", "pt_BR", "pt_PT",
+	"qb_DE", "qc_GT", "qe_US", "qk_DZ", "qr_GR", "qs_DE", "qt_US", "qu_PE", "qv_IT", "qz_MM",
+	"rm_CH", "rn_BI", "ro_RO", "ru_RU", "rw_RW",
+	"sa_IN", "sc_IT", "se_NO", "si_LK", "sk_SK", "sl_SI", "sn_ZW", "so_SO", "sq_AL", "sr_RS", "ss_SZ", "st_ZA", "su_ID", "sv_SE", "sw_KE", "sy_SY", "sz_PL",
+	"ta_IN", "te_IN", "tg_TJ", "th_TH", "ti_ET", "tk_TM", "tl_PH", "tl_ST", "tn_BW", "tq_AR", "tpi_PG", "tr_TR", "ts_ZA", "tt_RU", "tz_MA",
+	"uk_UA", "ur_PK", "uz_UZ",
+	"ve_ZA", "vi_VN",
+	"wo_SN",
+	"xh_ZA",
+	"yi_DE", "yo_NG",
+	"zh_CN", "zh_HK", "zh_TW", "zu_ZA", "zz_TR",
+}
+
+var localeIndex = func() map[string]string {
+	index := make(map[string]string, len(Locales))
+	for _, locale := range Locales {
+		index[strings.ToLower(locale)] = locale
+	}
+	return index
+}()
+
+// MatchLocale finds the supported locale closest to the given one, which may
+// use either "_" or "-" as a separator and any case. An exact match returns
+// true. Otherwise a locale for the same language is returned, preferring the
+// one where the region matches the language like fr_FR, or DefaultLocale if
+// there are none.
+func MatchLocale(locale string) (string, bool) {
+	match, exact := closestLocale(locale)
+	if match == "" {
+		return DefaultLocale, false
+	}
+	return match, exact
+}
+
+// Like MatchLocale, but returns an empty string if there is no locale for the
+// same language.
+func closestLocale(locale string) (string, bool) {
+	key := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "-", "_"))
+	if match, ok := localeIndex[key]; ok {
+		return match, true
+	}
+	language := strings.SplitN(key, "_", 2)[0]
+	if language == "" {
+		return "", false
+	}
+	if match, ok := localeIndex[language+"_"+language]; ok {
+		return match, false
+	}
+	for _, locale := range Locales {
+		if strings.HasPrefix(strings.ToLower(locale), language+"_") {
+			return locale, false
+		}
+	}
+	return "", false
+}
+
+// NegotiateLocale picks the supported locale best matching an Accept-Language
+// header. Languages are tried in order of preference, and an empty string is
+// returned if none are supported.
+func NegotiateLocale(acceptLanguage string) string {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool {
+		return choices[i].q > choices[j].q
+	})
+	for _, c := range choices {
+		if match, _ := closestLocale(c.tag); match != "" {
+			return match
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Preset is a named set of URL parameters, like {"server": "beta", "fedcm":
+// "true"}. Parameters in the URL override those in the preset.
+type Preset map[string]string
+
+// Get returns the value of a parameter, or an empty string.
+func (p Preset) Get(name string) string {
+	return p[name]
+}
+
+// Presets are selected by name using the preset URL parameter.
+type Presets map[string]Preset
+
+// Parameters which may be set by a Preset.
+var presetParams = map[string]bool{
+	"appid":                true,
+	"level":                true,
+	"locale":               true,
+	"server":               true,
+	"version":              true,
+	"view-mode":            true,
+	"status":               true,
+	"frictionlessRequests": true,
+	"init":                 true,
+	"customLogin":          true,
+	"fedcm":                true,
+	"fedcmAutoPrompt":      true,
+	"fedcmContext":         true,
+	"fedcmMode":            true,
+	"fedcmLoginHint":       true,
+	"fedcmDomainHint":      true,
+	"fedcmNonce":           true,
+}
+
+// LoadPresets reads presets from a JSON file mapping preset names to their
+// parameters:
+//
+//	{"fedcm-beta": {"server": "beta", "fedcm": "true"}}
+func LoadPresets(path string) (Presets, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read presets %s: %s", path, err)
+	}
+	var presets Presets
+	if err := json.Unmarshal(content, &presets); err != nil {
+		return nil, fmt.Errorf("Invalid presets %s: %s", path, err)
+	}
+	if err := presets.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid presets %s: %s", path, err)
+	}
+	return presets, nil
+}
+
+// Validate checks that presets only set known parameters to valid values.
+func (ps Presets) Validate() error {
+	names := make([]string, 0, len(ps))
+	for name := range ps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("preset with empty name")
+		}
+		for param, value := range ps[name] {
+			if !presetParams[param] {
+				return fmt.Errorf("preset %s: unknown parameter %s", name, param)
+			}
+			if param == "version" && FindVersion(value) == nil {
+				return fmt.Errorf("preset %s: unknown version %s", name, value)
+			}
+			if validate, ok := fedcmParams[param]; ok {
+				if err := validate(value); err != nil {
+					return fmt.Errorf("preset %s: %s", name, err)
+				}
+			}
+			if param == "locale" {
+				if _, exact := MatchLocale(value); !exact {
+					return fmt.Errorf("preset %s: unsupported locale %s", name, value)
+				}
+			}
+		}
+	}
+	return nil
+}
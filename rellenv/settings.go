@@ -0,0 +1,110 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SettingsCookieName is the cookie remembering settings across requests.
+const SettingsCookieName = "rell_settings"
+
+const settingsCookieMaxAge = 365 * 24 * time.Hour
+
+var (
+	// ErrNoSettingsSecret is returned by SettingsCookie when the app has no
+	// secret to sign the cookie with.
+	ErrNoSettingsSecret = errors.New("Remembering settings requires the app secret to be configured.")
+
+	errInvalidSettingsCookie  = errors.New("Ignoring invalid remembered settings.")
+	errUnsignedSettingsCookie = errors.New("Ignoring remembered settings, the app secret to verify them is not configured.")
+)
+
+// SettingsCookie returns a cookie remembering the settings in the request
+// values. Only the settings which differ from the defaults are stored. The
+// cookie is signed with the app secret, so without one it is refused.
+func (p *Parser) SettingsCookie(r *http.Request) (*http.Cookie, error) {
+	if len(p.App.SecretByte()) == 0 {
+		return nil, ErrNoSettingsSecret
+	}
+	e := p.Default()
+	applyValues(e, r.FormValue)
+	payload := base64.RawURLEncoding.EncodeToString(
+		[]byte(e.valuesFrom(e.defaults()).Encode()))
+	return &http.Cookie{
+		Name:     SettingsCookieName,
+		Value:    payload + "." + p.signSettings(payload),
+		Path:     "/",
+		MaxAge:   int(settingsCookieMaxAge / time.Second),
+		Secure:   p.Forwarded.Scheme(r) == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}, nil
+}
+
+// ClearSettingsCookie returns a cookie which removes the settings cookie.
+func (p *Parser) ClearSettingsCookie(r *http.Request) *http.Cookie {
+	return &http.Cookie{
+		Name:     SettingsCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   p.Forwarded.Scheme(r) == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Returns the remembered settings, or nil if there are none.
+func (p *Parser) readSettingsCookie(r *http.Request) (url.Values, error) {
+	cookie, err := r.Cookie(SettingsCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	if len(p.App.SecretByte()) == 0 {
+		return nil, errUnsignedSettingsCookie
+	}
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(p.signSettings(payload))) {
+		return nil, errInvalidSettingsCookie
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidSettingsCookie
+	}
+	values, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, errInvalidSettingsCookie
+	}
+	return values, nil
+}
+
+func (p *Parser) signSettings(payload string) string {
+	mac := hmac.New(sha256.New, p.App.SecretByte())
+	mac.Write([]byte(SettingsCookieName + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,143 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daaku/go.signedrequest/fbsr"
+)
+
+const (
+	signedRequestParam     = "signed_request"
+	signedRequestAlgorithm = "HMAC-SHA256"
+)
+
+// SignedRequestDiagnostics describes a signed_request, including the reason
+// it failed verification if it did.
+type SignedRequestDiagnostics struct {
+	Source    string                 `json:"source"` // the parameter or cookie name
+	Valid     bool                   `json:"valid"`
+	Error     string                 `json:"error,omitempty"`
+	Algorithm string                 `json:"algorithm,omitempty"`
+	IssuedAt  *time.Time             `json:"issuedAt,omitempty"`
+	Age       string                 `json:"age,omitempty"`
+	MaxAge    string                 `json:"maxAge"`
+	Fields    []string               `json:"fields,omitempty"`
+	Payload   map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Returns the name and value of the signed_request parameter, or failing
+// that the signed request cookie set by the JS SDK for the app.
+func signedRequestSource(r *http.Request, appID uint64) (string, string) {
+	if raw := r.FormValue(signedRequestParam); raw != "" {
+		return signedRequestParam, raw
+	}
+	name := fmt.Sprintf("fbsr_%d", appID)
+	if cookie, _ := r.Cookie(name); cookie != nil {
+		return name, cookie.Value
+	}
+	return "", ""
+}
+
+// Returned for apps without a secret, as anyone could sign with an empty key.
+var errNoAppSecret = errors.New("The app has no secret to verify signed requests with.")
+
+// Verifies the signature, age and algorithm of the signed request for the
+// app.
+func (p *Parser) unmarshalSignedRequest(raw string, appID uint64) (*fbsr.SignedRequest, error) {
+	secret := p.app(appID).SecretByte()
+	if len(secret) == 0 {
+		return nil, errNoAppSecret
+	}
+	sr, err := fbsr.Unmarshal([]byte(raw), secret, p.SignedRequestMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(sr.Algorithm, signedRequestAlgorithm) {
+		return nil, fmt.Errorf(
+			"Unsupported algorithm %q, expected %s.", sr.Algorithm, signedRequestAlgorithm)
+	}
+	return sr, nil
+}
+
+// DiagnoseSignedRequest verifies the signed request found in the request for
+// the given app, and decodes the payload even if verification fails. It
+// returns nil if the request does not include a signed request.
+func (p *Parser) DiagnoseSignedRequest(r *http.Request, appID uint64, now time.Time) *SignedRequestDiagnostics {
+	source, raw := signedRequestSource(r, appID)
+	if raw == "" {
+		return nil
+	}
+	d := &SignedRequestDiagnostics{
+		Source: source,
+		MaxAge: p.SignedRequestMaxAge.String(),
+	}
+	if _, err := p.unmarshalSignedRequest(raw, appID); err != nil {
+		d.Error = err.Error()
+	} else {
+		d.Valid = true
+	}
+
+	payload, err := decodeSignedRequestPayload(raw)
+	if err != nil {
+		if d.Error == "" {
+			d.Error = err.Error()
+		}
+		return d
+	}
+	d.Payload = payload
+	for field := range payload {
+		d.Fields = append(d.Fields, field)
+	}
+	sort.Strings(d.Fields)
+	d.Algorithm, _ = payload["algorithm"].(string)
+	if issuedAt, ok := payload["issued_at"].(float64); ok && issuedAt > 0 {
+		t := time.Unix(int64(issuedAt), 0)
+		d.IssuedAt = &t
+		d.Age = now.Sub(t).Truncate(time.Second).String()
+	}
+	return d
+}
+
+// Decodes the payload of a signed request without verifying it.
+func decodeSignedRequestPayload(raw string) (map[string]interface{}, error) {
+	dot := strings.IndexByte(raw, '.')
+	if dot == -1 {
+		return nil, errors.New("Could not find dot separator in signed request.")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(raw[dot+1:], "="))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode payload as base64 data: %s", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, fmt.Errorf("Could not decode payload as JSON: %s", err)
+	}
+	return payload, nil
+}
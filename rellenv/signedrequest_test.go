@@ -0,0 +1,175 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/rellenv"
+)
+
+const signedRequestSecret = "secret"
+
+func signRequest(t *testing.T, secret string, payload map[string]interface{}) string {
+	b, err := json.Marshal(payload)
+	ensure.Nil(t, err)
+	encoded := base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) + "." + encoded
+}
+
+func signedRequestParser() *rellenv.Parser {
+	p := defaultParser()
+	p.App = fbapp.New(defaultFacebookAppID, signedRequestSecret, "")
+	p.SignedRequestMaxAge = time.Hour
+	return p
+}
+
+func signedRequestForm(t *testing.T, sr string) *http.Request {
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/info/signed-request", nil)
+	ensure.Nil(t, err)
+	req.Form = url.Values{"signed_request": []string{sr}}
+	return req
+}
+
+func TestDiagnoseSignedRequest(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	issued := now.Add(-time.Minute)
+	cases := []struct {
+		name    string
+		secret  string
+		payload map[string]interface{}
+		err     string
+	}{
+		{
+			name:    "valid",
+			secret:  signedRequestSecret,
+			payload: map[string]interface{}{"algorithm": "HMAC-SHA256", "issued_at": issued.Unix()},
+		},
+		{
+			name:    "bad signature",
+			secret:  "other",
+			payload: map[string]interface{}{"algorithm": "HMAC-SHA256", "issued_at": issued.Unix()},
+			err:     "Invalid signature",
+		},
+		{
+			name:    "expired",
+			secret:  signedRequestSecret,
+			payload: map[string]interface{}{"algorithm": "HMAC-SHA256", "issued_at": now.Add(-2 * time.Hour).Unix()},
+			err:     "expired",
+		},
+		{
+			name:    "wrong algorithm",
+			secret:  signedRequestSecret,
+			payload: map[string]interface{}{"algorithm": "HMAC-SHA1", "issued_at": issued.Unix()},
+			err:     "Unsupported algorithm",
+		},
+	}
+	for _, c := range cases {
+		req := signedRequestForm(t, signRequest(t, c.secret, c.payload))
+		d := signedRequestParser().DiagnoseSignedRequest(req, defaultFacebookAppID, now)
+		ensure.NotNil(t, d, c.name)
+		ensure.DeepEqual(t, d.Source, "signed_request", c.name)
+		ensure.DeepEqual(t, d.Valid, c.err == "", c.name)
+		ensure.StringContains(t, d.Error, c.err, c.name)
+		ensure.DeepEqual(t, d.Algorithm, c.payload["algorithm"], c.name)
+		ensure.DeepEqual(t, d.Fields, []string{"algorithm", "issued_at"}, c.name)
+		ensure.DeepEqual(t, d.IssuedAt.Unix(), c.payload["issued_at"], c.name)
+	}
+}
+
+func TestDiagnoseSignedRequestAge(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	sr := signRequest(t, signedRequestSecret, map[string]interface{}{
+		"algorithm": "HMAC-SHA256",
+		"issued_at": now.Add(-90 * time.Second).Unix(),
+	})
+	d := signedRequestParser().DiagnoseSignedRequest(signedRequestForm(t, sr), defaultFacebookAppID, now)
+	ensure.DeepEqual(t, d.Age, "1m30s")
+	ensure.DeepEqual(t, d.MaxAge, "1h0m0s")
+}
+
+func TestDiagnoseSignedRequestUndecodable(t *testing.T) {
+	t.Parallel()
+	d := signedRequestParser().DiagnoseSignedRequest(
+		signedRequestForm(t, "no-dot"), defaultFacebookAppID, time.Now())
+	ensure.False(t, d.Valid)
+	ensure.StringContains(t, d.Error, "dot separator")
+	ensure.True(t, d.Payload == nil)
+}
+
+func TestDiagnoseSignedRequestCookie(t *testing.T) {
+	t.Parallel()
+	p := signedRequestParser()
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/info/signed-request", nil)
+	ensure.Nil(t, err)
+	ensure.True(t, p.DiagnoseSignedRequest(req, defaultFacebookAppID, time.Now()) == nil)
+
+	req.AddCookie(&http.Cookie{Name: "fbsr_42", Value: "x.y"})
+	d := p.DiagnoseSignedRequest(req, defaultFacebookAppID, time.Now())
+	ensure.DeepEqual(t, d.Source, "fbsr_42")
+	ensure.False(t, d.Valid)
+}
+
+func TestSignedRequestCookieNoSecret(t *testing.T) {
+	t.Parallel()
+	req, err := http.NewRequest("GET", "http://www.fbrell.com/", nil)
+	ensure.Nil(t, err)
+	req.AddCookie(&http.Cookie{Name: "fbsr_42", Value: "x.y"})
+	env, err := defaultParser().FromRequest(req)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(env.Warnings()), 0)
+}
+
+func TestSignedRequestViewMode(t *testing.T) {
+	t.Parallel()
+	p := signedRequestParser()
+	issued := time.Now().Unix()
+	sr := signRequest(t, signedRequestSecret, map[string]interface{}{
+		"algorithm": "HMAC-SHA256",
+		"issued_at": issued,
+		"page":      map[string]interface{}{"id": "123"},
+	})
+	env, err := p.FromRequest(signedRequestForm(t, sr))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.PageTab)
+
+	sr = signRequest(t, signedRequestSecret, map[string]interface{}{
+		"algorithm": "HMAC-SHA1",
+		"issued_at": issued,
+	})
+	env, err = p.FromRequest(signedRequestForm(t, sr))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, env.ViewMode, rellenv.Website)
+	ensure.DeepEqual(t, len(env.Warnings()), 1)
+}
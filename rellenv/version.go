@@ -0,0 +1,80 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package rellenv
+
+import (
+	"regexp"
+	"time"
+)
+
+// Version is a Graph API and JS SDK version.
+type Version struct {
+	Name       string    // e.g. "v25.0"
+	Released   time.Time // when the version became available
+	Deprecated time.Time // when calls stop working, zero if not yet announced
+}
+
+// IsDeprecated returns true if the version is deprecated at the given time.
+func (v *Version) IsDeprecated(now time.Time) bool {
+	return !v.Deprecated.IsZero() && !now.Before(v.Deprecated)
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Versions are the known versions, oldest first. The last one is the default.
+var Versions = []*Version{
+	{Name: "v17.0", Released: day(2023, time.May, 23), Deprecated: day(2025, time.September, 12)},
+	{Name: "v18.0", Released: day(2023, time.September, 12), Deprecated: day(2026, time.January, 26)},
+	{Name: "v19.0", Released: day(2024, time.January, 23), Deprecated: day(2026, time.May, 21)},
+	{Name: "v20.0", Released: day(2024, time.May, 21), Deprecated: day(2026, time.September, 24)},
+	{Name: "v21.0", Released: day(2024, time.October, 2), Deprecated: day(2027, time.February, 3)},
+	{Name: "v22.0", Released: day(2025, time.January, 21)},
+	{Name: "v23.0", Released: day(2025, time.May, 29)},
+	{Name: "v24.0", Released: day(2025, time.October, 8)},
+	{Name: "v25.0", Released: day(2026, time.February, 18)},
+}
+
+// DefaultVersion is the version used when none is specified.
+var DefaultVersion = Versions[len(Versions)-1]
+
+var versionRegexp = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?$`)
+
+// FindVersion returns the known version for name, or nil. Names are
+// normalized, so "25", "v25" and "V25.0" all find v25.0.
+func FindVersion(name string) *Version {
+	m := versionRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	minor := m[2]
+	if minor == "" {
+		minor = "0"
+	}
+	normalized := "v" + m[1] + "." + minor
+	for _, v := range Versions {
+		if v.Name == normalized {
+			return v
+		}
+	}
+	return nil
+}
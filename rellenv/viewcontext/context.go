@@ -22,19 +22,38 @@
 package viewcontext
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/daaku/ctxerr"
 	"github.com/daaku/go.httpdev"
+	"github.com/fbsamples/fbrell/errcode"
 	"github.com/fbsamples/fbrell/rellenv"
 )
 
 var rev string
 
-type Handler struct{}
+const (
+	// SettingsPath is where the settings cookie endpoints are served.
+	SettingsPath = "/settings/"
+
+	// SignedRequestPath is where signed request diagnostics are served.
+	SignedRequestPath = "/info/signed-request"
+)
+
+type Handler struct {
+	EnvParser *rellenv.Parser
+}
 
 // Handler for /info/ to see a JSON view of some server context.
 func (h *Handler) Info(w http.ResponseWriter, r *http.Request) error {
+	if r.URL.Path == SignedRequestPath {
+		return h.SignedRequest(w, r)
+	}
 	ctx := r.Context()
 	env, err := rellenv.FromContext(ctx)
 	if err != nil {
@@ -48,6 +67,104 @@ func (h *Handler) Info(w http.ResponseWriter, r *http.Request) error {
 		"rev":            rev,
 		"runtimeVersion": runtime.Version(),
 	}
+	// the request warnings are already part of the context
+	if warnings := versionWarnings(env, time.Now()); len(warnings) > 0 {
+		info["warnings"] = warnings
+	}
+	httpdev.Info(info, w, r)
+	return nil
+}
+
+// SignedRequest shows why the signed_request in the request did or did not
+// verify, along with the decoded payload.
+func (h *Handler) SignedRequest(w http.ResponseWriter, r *http.Request) error {
+	appID := rellenv.FbApp(r.Context()).ID()
+	info := map[string]interface{}{}
+	if d := h.EnvParser.DiagnoseSignedRequest(r, appID, time.Now()); d != nil {
+		info["signedRequest"] = d
+	} else {
+		info["error"] = fmt.Sprintf(
+			"No signed_request parameter or fbsr_%d cookie found.", appID)
+	}
 	httpdev.Info(info, w, r)
 	return nil
 }
+
+// Remember stores the settings given in the request in the settings cookie,
+// and redirects to the path in the next parameter. Only same origin POSTs
+// are accepted.
+func (h *Handler) Remember(w http.ResponseWriter, r *http.Request) error {
+	if err := checkSameOrigin(r); err != nil {
+		return err
+	}
+	cookie, err := h.EnvParser.SettingsCookie(r)
+	if err != nil {
+		return ctxerr.Wrap(r.Context(), errcode.Add(http.StatusForbidden, err))
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, nextURL(r), http.StatusSeeOther)
+	return nil
+}
+
+// Reset clears the settings cookie, and redirects to the path in the next
+// parameter. Only same origin POSTs are accepted.
+func (h *Handler) Reset(w http.ResponseWriter, r *http.Request) error {
+	if err := checkSameOrigin(r); err != nil {
+		return err
+	}
+	http.SetCookie(w, h.EnvParser.ClearSettingsCookie(r))
+	http.Redirect(w, r, nextURL(r), http.StatusSeeOther)
+	return nil
+}
+
+// Rejects requests which are not POSTs from a page on this site, going by
+// the Origin header or failing that the Referer, so other sites can not
+// change the settings.
+func checkSameOrigin(r *http.Request) error {
+	ctx := r.Context()
+	if r.Method != "POST" {
+		return ctxerr.Wrap(ctx, errcode.New(http.StatusMethodNotAllowed,
+			"Settings can only be changed with a POST."))
+	}
+	env, err := rellenv.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(source)
+	if source == "" || err != nil || u.Scheme != env.Scheme || u.Host != env.Host {
+		return ctxerr.Wrap(ctx, errcode.New(http.StatusForbidden,
+			"Settings can only be changed from %s://%s.", env.Scheme, env.Host))
+	}
+	return nil
+}
+
+// Returns the next parameter if it is a local URL, or the root.
+func nextURL(r *http.Request) string {
+	next := r.FormValue("next")
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") ||
+		strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return "/"
+	}
+	return next
+}
+
+// Warns about the selected version if it is deprecated.
+func versionWarnings(env *rellenv.Env, now time.Time) []rellenv.Warning {
+	version := rellenv.FindVersion(env.Version)
+	if version == nil || !version.IsDeprecated(now) {
+		return nil
+	}
+	return []rellenv.Warning{{
+		Param: "version",
+		Value: version.Name,
+		Message: fmt.Sprintf(
+			"Version %s was deprecated on %s, use %s instead.",
+			version.Name, version.Deprecated.Format("2006-01-02"),
+			rellenv.DefaultVersion.Name),
+	}}
+}
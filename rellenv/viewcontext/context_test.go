@@ -0,0 +1,111 @@
+/**
+ * Copyright (c) 2014-present, Facebook, Inc. All rights reserved.
+ *
+ * You are hereby granted a non-exclusive, worldwide, royalty-free license to use,
+ * copy, modify, and distribute this software in source code or binary form for use
+ * in connection with the web services and APIs provided by Facebook.
+ *
+ * As with any software that integrates with the Facebook platform, your use of
+ * this software is subject to the Facebook Developer Principles and Policies
+ * [http://developers.facebook.com/policy/]. This copyright notice shall be
+ * included in all copies or substantial portions of the software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ * FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ * COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ * IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ * CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package viewcontext_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/go.trustforward"
+	"github.com/facebookgo/ensure"
+	"github.com/facebookgo/fbapp"
+	"github.com/fbsamples/fbrell/rellenv"
+	"github.com/fbsamples/fbrell/rellenv/viewcontext"
+)
+
+type noLookups struct{}
+
+func (noLookups) Check(ctx context.Context, uid uint64) bool { return false }
+
+func (noLookups) Get(ctx context.Context, id uint64) string { return "" }
+
+func settingsParser(secret string) *rellenv.Parser {
+	return &rellenv.Parser{
+		EmpChecker:   noLookups{},
+		AppNSFetcher: noLookups{},
+		App:          fbapp.New(42, secret, ""),
+		Forwarded:    &trustforward.Forwarded{},
+	}
+}
+
+func settingsRequest(t *testing.T, p *rellenv.Parser, method, target string,
+	header http.Header) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	env, err := p.FromRequest(r)
+	ensure.Nil(t, err)
+	return r.WithContext(rellenv.WithEnv(r.Context(), env))
+}
+
+func errCode(err error) int {
+	if code, ok := err.(interface{ Code() int }); ok {
+		return code.Code()
+	}
+	if wrapper, ok := err.(interface{ Underlying() error }); ok {
+		return errCode(wrapper.Underlying())
+	}
+	return 0
+}
+
+func TestRememberSameOrigin(t *testing.T) {
+	t.Parallel()
+	p := settingsParser("secret")
+	h := &viewcontext.Handler{EnvParser: p}
+	const target = "http://www.fbrell.com/settings/remember?server=beta&next=/x"
+	cases := []struct {
+		method string
+		header http.Header
+		code   int
+	}{
+		{"POST", http.Header{"Origin": {"http://www.fbrell.com"}}, 0},
+		{"POST", http.Header{"Referer": {"http://www.fbrell.com/examples/"}}, 0},
+		{"POST", http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+		{"POST", http.Header{"Origin": {"null"}}, http.StatusForbidden},
+		{"POST", nil, http.StatusForbidden},
+		{"GET", http.Header{"Origin": {"http://www.fbrell.com"}}, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		err := h.Remember(w, settingsRequest(t, p, c.method, target, c.header))
+		ensure.DeepEqual(t, errCode(err), c.code, c.method, c.header)
+		ensure.DeepEqual(t, w.Header().Get("Set-Cookie") != "", c.code == 0, c.header)
+
+		w = httptest.NewRecorder()
+		err = h.Reset(w, settingsRequest(t, p, c.method, target, c.header))
+		ensure.DeepEqual(t, errCode(err), c.code, c.method, c.header)
+	}
+}
+
+func TestRememberNoSecret(t *testing.T) {
+	t.Parallel()
+	p := settingsParser("")
+	h := &viewcontext.Handler{EnvParser: p}
+	w := httptest.NewRecorder()
+	err := h.Remember(w, settingsRequest(t, p, "POST",
+		"http://www.fbrell.com/settings/remember?server=beta",
+		http.Header{"Origin": {"http://www.fbrell.com"}}))
+	ensure.DeepEqual(t, errCode(err), http.StatusForbidden)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), "")
+}
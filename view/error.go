@@ -21,6 +21,7 @@
 package view
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
@@ -29,6 +30,10 @@ import (
 	"github.com/fbsamples/fbrell/errcode"
 )
 
+// APIPath is the prefix for the JSON APIs. Errors for requests under it are
+// rendered as JSON.
+const APIPath = "/api/"
+
 type ErrorCode interface // HTTP Coded Error.
 {
 	error
@@ -41,14 +46,22 @@ type errorCodeHandler struct {
 }
 
 // Serve an appropriate response for this error. Currently this means
-// HTML or Plain Text.
-// TODO(naitik): Extend for JSON.
+// JSON, HTML or Plain Text.
 func (err errorCodeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	code := err.err.Code()
 	if code == 0 {
 		code = http.StatusInternalServerError
 	}
-	if usePlainText(r) {
+	if useJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    code,
+				"message": err.err.Error(),
+			},
+		})
+	} else if usePlainText(r) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(code)
 		_, _ = io.Copy(w, strings.NewReader(err.err.Error()))
@@ -72,7 +85,7 @@ func (err errorCodeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func Error(w http.ResponseWriter, r *http.Request, err error) {
 	handler, ok := err.(http.Handler)
 	if !ok {
-		errCode, ok := err.(ErrorCode)
+		errCode, ok := findErrorCode(err)
 		if !ok {
 			errCode = errcode.Add(500, err)
 		}
@@ -83,6 +96,27 @@ func Error(w http.ResponseWriter, r *http.Request, err error) {
 	handler.ServeHTTP(w, r)
 }
 
+// Finds an ErrorCode, looking through errors wrapped by ctxerr.
+func findErrorCode(err error) (ErrorCode, bool) {
+	for err != nil {
+		if errCode, ok := err.(ErrorCode); ok {
+			return errCode, true
+		}
+		wrapper, ok := err.(interface{ Underlying() error })
+		if !ok {
+			break
+		}
+		err = wrapper.Underlying()
+	}
+	return nil, false
+}
+
+// JSON is used for the API as well as for clients that explicitly ask for it.
+func useJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, APIPath) ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
 func usePlainText(r *http.Request) bool {
 	return strings.Contains(r.UserAgent(), "curl")
 }
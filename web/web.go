@@ -31,9 +31,9 @@ import (
 	"github.com/daaku/ctxmux"
 	"github.com/daaku/go.signedrequest/appdata"
 	static "github.com/daaku/go.static"
-	"github.com/facebookgo/fbapp"
 	"github.com/fbsamples/fbrell/adminweb"
 	"github.com/fbsamples/fbrell/examples/viewexamples"
+	"github.com/fbsamples/fbrell/mockcanvas"
 	"github.com/fbsamples/fbrell/mockoauth"
 	"github.com/fbsamples/fbrell/mockpartner/capisetup"
 	"github.com/fbsamples/fbrell/mockpartner/jobseasyapply"
@@ -47,7 +47,6 @@ import (
 // The rell web application.
 type Handler struct {
 	Logger              *log.Logger
-	App                 fbapp.App
 	SignedRequestMaxAge time.Duration
 	EnvParser           *rellenv.Parser
 	PublicFS            http.FileSystem
@@ -61,6 +60,7 @@ type Handler struct {
 	JobsEasyApplyHandler *jobseasyapply.Handler
 	Static               *static.Handler
 	AdminHandler         *adminweb.Handler
+	MockCanvasHandler    *mockcanvas.Handler // only set in development
 
 	mux http.Handler
 }
@@ -89,7 +89,13 @@ func (a *Handler) Init() error {
 	mux.GET(public+"*rest", ctxmux.HTTPHandler(http.StripPrefix(public, fileserver)))
 	mux.GET("/info/*rest", a.ContextHandler.Info)
 	mux.POST("/info/*rest", a.ContextHandler.Info)
+	mux.POST(viewcontext.SettingsPath+"remember", a.ContextHandler.Remember)
+	mux.POST(viewcontext.SettingsPath+"reset", a.ContextHandler.Reset)
 	mux.GET("/examples/", a.ExamplesHandler.List)
+	mux.GET(viewexamples.SearchPath, a.ExamplesHandler.Search)
+	mux.POST("/saved/", a.ExamplesHandler.Save)
+	mux.GET(viewexamples.APIPath, a.ExamplesHandler.APIList)
+	mux.GET(viewexamples.APIPath+"/*rest", a.ExamplesHandler.APIExample)
 	mux.GET("/og/*rest", a.OgHandler.Values)
 	mux.GET("/rog/*rest", a.OgHandler.Base64)
 	mux.GET("/rog-redirect/*rest", a.OgHandler.Redirect)
@@ -101,16 +107,25 @@ func (a *Handler) Init() error {
 	mux.GET(jobseasyapply.Path+"*rest", a.JobsEasyApplyHandler.Handle)
 	mux.POST(jobseasyapply.Path+"*rest", a.JobsEasyApplyHandler.Handle)
 
+	if a.MockCanvasHandler != nil {
+		mux.GET(mockcanvas.Path+"*rest", a.MockCanvasHandler.Handle)
+		mux.POST(mockcanvas.Path+"*rest", a.MockCanvasHandler.Handle)
+	}
+
 	if a.AdminHandler.Path != "" {
 		adminPath := path.Join("/", a.AdminHandler.Path) + "/*rest"
 		mux.GET(adminPath, ctxmux.HTTPHandler(a.AdminHandler))
 	}
 
-	a.mux = &appdata.Handler{
-		Handler: mux,
-		Secret:  a.App.SecretByte(),
-		MaxAge:  a.SignedRequestMaxAge,
-	}
+	// the secret depends on the app selected by the request
+	a.mux = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := &appdata.Handler{
+			Handler: mux,
+			Secret:  a.EnvParser.SignedRequestSecret(r),
+			MaxAge:  a.SignedRequestMaxAge,
+		}
+		h.ServeHTTP(w, r)
+	})
 	return nil
 }
 